@@ -31,6 +31,17 @@ func (s *Conditions) UpdateCondition(conditionType string, conditionStatus metav
 	}, s.Conditions)
 }
 
+func (s *Conditions) RemoveCondition(conditionType string) {
+	var conditions []metav1.Condition
+	for _, c := range s.Conditions {
+		if c.Type != conditionType {
+			conditions = append(conditions, c)
+		}
+	}
+
+	s.Conditions = conditions
+}
+
 func (s *Conditions) SetReady(conditionStatus metav1.ConditionStatus, msg ...string) {
 	s.UpdateCondition(apis.ReconcileSuccess, conditionStatus, apis.ReconcileSuccessReason, getOpt(0, msg...))
 }
@@ -44,6 +55,15 @@ func (s *Conditions) IsReady() bool {
 	return c.Status == metav1.ConditionTrue
 }
 
+func (s *Conditions) GetCondition(ct string) *metav1.Condition {
+	c, exists := apis.GetCondition(ct, s.Conditions)
+	if !exists {
+		return nil
+	}
+
+	return &c
+}
+
 func (s *Conditions) ConditionMsg(ct string) string {
 	c, exists := apis.GetCondition(ct, s.Conditions)
 	if !exists {
@@ -17,24 +17,43 @@ limitations under the License.
 package v1alpha1
 
 import (
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 // KeycloakSpec defines the desired state of Keycloak
 type KeycloakSpec struct {
 	// +required
-	// PostgreSQL Database configurations
-	Database *PostgresDatabase `json:"database"`
+	// Database configurations, PostgreSQL unless another vendor is set
+	Database *Database `json:"database"`
 
 	// +optional
 	// Extra options to load, or override existing ENVs
 	AdditionalOptions []SecretOptionVar `json:"additionalOptions,omitempty"`
 
+	// +optional
+	// Load every key of Secrets or ConfigMaps as options, additionalOptions take precedence
+	EnvFrom []v1.EnvFromSource `json:"envFrom,omitempty"`
+
+	// +optional
+	// Extra volumes of the Keycloak pods, names must not clash with the volumes of the operator
+	Volumes []v1.Volume `json:"volumes,omitempty"`
+
+	// +optional
+	// Extra volume mounts of the Keycloak container
+	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
+
 	// +required
-	// Number of instances
+	// Number of instances, ignored while autoscaling
 	Instances *int32 `json:"instances"`
 
+	// +optional
+	// Scale the StatefulSet with a HorizontalPodAutoscaler, resources are sized for the minimum replicas
+	Autoscaling *AutoscalingSpec `json:"autoscaling,omitempty"`
+
 	// +optional
 	// Trusted CA bundle from configmap
 	TrustedCABundles *v1.LocalObjectReference `json:"trustedCABundles,omitempty"`
@@ -47,15 +66,322 @@ type KeycloakSpec struct {
 	// Custom providers & SPIs to add to the RHBK installation
 	Providers []Provider `json:"providers,omitempty"`
 
+	// +optional
+	// Maven repositories providers are downloaded from
+	MavenRepositories []MavenRepository `json:"mavenRepositories,omitempty"`
+
+	// +optional
+	// Persistent cache of providers with a sha256, they are not downloaded again while the cached file matches
+	ProviderCache *ProviderCacheSpec `json:"providerCache,omitempty"`
+
+	// +optional
+	// Custom themes copied from images or mounted from ConfigMaps
+	Themes []Theme `json:"themes,omitempty"`
+
 	// Configurations for hostname related options
 	// Default proxy is false
 	NetworkConfig *NetworkConfig `json:"networkOptions,omitempty"`
 
 	// +optional
-	// Realm sizing
+	// Realm sizing, the sizing of the imports targeting the instance is added to it
 	Sizing *RealmSizing `json:"sizing,omitempty"`
+
+	// +optional
+	// Sizing assumed for imports declaring none, they add no load when not set
+	DefaultRealmSizing *RealmSizing `json:"defaultRealmSizing,omitempty"`
+
+	// +optional
+	// Requests and limits set on top of the sizing result, e.g. ephemeral-storage or an exact memory limit
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+
+	// +optional
+	// RHBK image to run, overrides the operator default and RELATED_IMAGE_RHBK
+	Image string `json:"image,omitempty"`
+
+	// +optional
+	// How pods are replaced when the image changes
+	Update *UpdateSpec `json:"update,omitempty"`
+
+	// +optional
+	// Features to enable or disable, names are validated against the features of the image version
+	Features *Features `json:"features,omitempty"`
+
+	// +optional
+	// Expose Keycloak through an Ingress instead of an OpenShift Route, networkOptions.hostname is required.
+	// Clusters without the Route API always use an Ingress.
+	Ingress *IngressSpec `json:"ingress,omitempty"`
+
+	// +optional
+	// Certificate of the HTTPS endpoint, the OpenShift service CA signs <name>-tls when not set
+	TLS *TLSSpec `json:"tls,omitempty"`
+
+	// +optional
+	// Placement of the pods, import jobs are scheduled alike
+	Scheduling *SchedulingSpec `json:"scheduling,omitempty"`
+
+	// +optional
+	// PodDisruptionBudget of instances with more than one pod
+	DisruptionBudget *DisruptionBudgetSpec `json:"disruptionBudget,omitempty"`
+
+	// +optional
+	// Probe timings and graceful shutdown of the pods
+	Lifecycle *LifecycleSpec `json:"lifecycle,omitempty"`
+
+	// +optional
+	// Customisations outside of what the operator supports, use at your own risk
+	Unsupported *UnsupportedSpec `json:"unsupported,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="!has(self.shutdownDelay) || !has(self.terminationGracePeriodSeconds) || duration(self.shutdownDelay) < duration(string(self.terminationGracePeriodSeconds) + 's')",message="terminationGracePeriodSeconds must exceed shutdownDelay"
+type LifecycleSpec struct {
+	// +optional
+	// Defaults to a period of 10s and 3 failures
+	LivenessProbe *ProbeTimings `json:"livenessProbe,omitempty"`
+
+	// +optional
+	// Defaults to a period of 10s and 3 failures
+	ReadinessProbe *ProbeTimings `json:"readinessProbe,omitempty"`
+
+	// +optional
+	// Defaults to a period of 1s and 600 failures, the time given to start and migrate the database
+	StartupProbe *ProbeTimings `json:"startupProbe,omitempty"`
+
+	// +optional
+	// Time a terminating pod keeps serving while it is removed from the endpoints of routers and load balancers.
+	// Defaults to 10s, 0s disables the preStop hook.
+	ShutdownDelay *metav1.Duration `json:"shutdownDelay,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// Time given to a pod to shut down, defaults to the shutdown delay plus 30s to finish in-flight requests
+	TerminationGracePeriodSeconds *int64 `json:"terminationGracePeriodSeconds,omitempty"`
+}
+
+// ProbeTimings overrides the timings of a probe, unset fields keep the defaults
+type ProbeTimings struct {
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	InitialDelaySeconds *int32 `json:"initialDelaySeconds,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	PeriodSeconds *int32 `json:"periodSeconds,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	FailureThreshold *int32 `json:"failureThreshold,omitempty"`
+}
+
+type UnsupportedSpec struct {
+	// +optional
+	// +kubebuilder:pruning:PreserveUnknownFields
+	// +kubebuilder:validation:Schemaless
+	// +kubebuilder:validation:Type=object
+	// Merged by strategic merge patch onto the pod template of the operator, import jobs get the same overlay.
+	// Sidecars must be init containers with restartPolicy Always, or import jobs never complete.
+	PodTemplate *v1.PodTemplateSpec `json:"podTemplate,omitempty"`
+}
+
+func (s *KeycloakSpec) GetPodTemplate() *v1.PodTemplateSpec {
+	if s.Unsupported == nil {
+		return nil
+	}
+
+	return s.Unsupported.PodTemplate
+}
+
+type DisruptionBudgetSpec struct {
+	// +optional
+	// +kubebuilder:validation:XIntOrString
+	// Pods evicted at once, a number or a percentage of the instances. Defaults to 1.
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
+}
+
+type SchedulingSpec struct {
+	// +optional
+	NodeSelector map[string]string `json:"nodeSelector,omitempty"`
+
+	// +optional
+	Tolerations []v1.Toleration `json:"tolerations,omitempty"`
+
+	// +optional
+	// Replaces the default preferred anti-affinity between the pods of the instance, {} disables it
+	Affinity *v1.Affinity `json:"affinity,omitempty"`
+
+	// +optional
+	// Replaces the default best effort spread of the pods across zones
+	TopologySpreadConstraints []v1.TopologySpreadConstraint `json:"topologySpreadConstraints,omitempty"`
+
+	// +optional
+	PriorityClassName string `json:"priorityClassName,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="has(self.secretName) != has(self.issuerRef)",message="exactly one of secretName or issuerRef is required"
+type TLSSpec struct {
+	// +optional
+	// Existing kubernetes.io/tls secret, it should cover <name>-svc.<namespace>.svc when a route reencrypts
+	SecretName string `json:"secretName,omitempty"`
+
+	// +optional
+	// cert-manager issuer of a Certificate covering the service and external hostnames, stored in <name>-tls
+	IssuerRef *IssuerReference `json:"issuerRef,omitempty"`
+
+	// +optional
+	// How often Keycloak reloads the certificate files, rotated certificates are served without a restart.
+	// Defaults to 1h.
+	ReloadPeriod *metav1.Duration `json:"reloadPeriod,omitempty"`
+}
+
+type IssuerReference struct {
+	Name string `json:"name"`
+
+	// +optional
+	// +kubebuilder:validation:Enum=Issuer;ClusterIssuer
+	// +kubebuilder:default=Issuer
+	Kind string `json:"kind,omitempty"`
+
+	// +optional
+	// +kubebuilder:default=cert-manager.io
+	Group string `json:"group,omitempty"`
+}
+
+type IngressSpec struct {
+	// +optional
+	// Ingress class, the cluster default class is used when not set
+	ClassName *string `json:"className,omitempty"`
+
+	// +optional
+	// Annotations of the Ingress, nginx.ingress.kubernetes.io/backend-protocol defaults to HTTPS
+	Annotations map[string]string `json:"annotations,omitempty"`
+
+	// +optional
+	// Secret holding the certificate of the hostname, the ingress controller default is used when not set
+	TLSSecret string `json:"tlsSecret,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="self.maxReplicas >= self.minReplicas",message="maxReplicas must not be lower than minReplicas"
+type AutoscalingSpec struct {
+	// +kubebuilder:validation:Minimum=1
+	MinReplicas int32 `json:"minReplicas"`
+
+	// +kubebuilder:validation:Minimum=1
+	MaxReplicas int32 `json:"maxReplicas"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// Average CPU utilization of the pods in percent of their requests, defaults to 80 when no metrics are set
+	TargetCPUUtilizationPercentage *int32 `json:"targetCPUUtilizationPercentage,omitempty"`
+
+	// +optional
+	// Additional metrics, e.g. pod metrics of Keycloak served by a custom metrics API
+	Metrics []autoscalingv2.MetricSpec `json:"metrics,omitempty"`
 }
 
+// GetMinInstances returns the instances resources are sized for
+func (s *KeycloakSpec) GetMinInstances() *int32 {
+	if s.Autoscaling != nil {
+		return &s.Autoscaling.MinReplicas
+	}
+
+	return s.Instances
+}
+
+// GetMaxInstances returns the instances that may run at once
+func (s *KeycloakSpec) GetMaxInstances() int32 {
+	if s.Autoscaling != nil {
+		return s.Autoscaling.MaxReplicas
+	}
+
+	if s.Instances == nil {
+		return 0
+	}
+
+	return *s.Instances
+}
+
+// GetHostname returns the configured hostname, empty if it is left to the platform
+func (s *KeycloakSpec) GetHostname() string {
+	if s.NetworkConfig == nil {
+		return ""
+	}
+
+	return s.NetworkConfig.Hostname
+}
+
+type UpdateStrategy string
+
+const (
+	// UpdateStrategyAuto runs an update compatibility check and picks RollingUpdate or Recreate from its result,
+	// the upgrade is blocked when the check cannot run, e.g. the running image has no update-compatibility command
+	UpdateStrategyAuto UpdateStrategy = "Auto"
+	// UpdateStrategyRollingUpdate replaces pods one at a time, old and new versions run side by side
+	UpdateStrategyRollingUpdate UpdateStrategy = "RollingUpdate"
+	// UpdateStrategyRecreate stops all pods before starting the new version
+	UpdateStrategyRecreate UpdateStrategy = "Recreate"
+)
+
+type UpdateSpec struct {
+	// +optional
+	// +kubebuilder:validation:Enum=Auto;RollingUpdate;Recreate
+	// +kubebuilder:default=Auto
+	// Strategy used when the image changes, defaults to Auto
+	Strategy UpdateStrategy `json:"strategy,omitempty"`
+}
+
+func (s *KeycloakSpec) GetUpdateStrategy() UpdateStrategy {
+	if s.Update == nil || s.Update.Strategy == "" {
+		return UpdateStrategyAuto
+	}
+
+	return s.Update.Strategy
+}
+
+const (
+	UpgradeCondition                string = "Upgrading"
+	UpgradeReasonCompatibilityCheck string = "CompatibilityCheck"
+	UpgradeReasonRollingUpdate      string = "RollingUpdate"
+	UpgradeReasonRecreate           string = "Recreate"
+	UpgradeReasonCompleted          string = "Completed"
+	UpgradeReasonCheckFailed        string = "CheckFailed"
+
+	DatabaseReadyCondition        string = "DatabaseReady"
+	DatabaseReasonConnected       string = "Connected"
+	DatabaseReasonConnectionError string = "ConnectionError"
+
+	ProvidersVerifiedCondition      string = "ProvidersVerified"
+	ProvidersReasonVerified         string = "Verified"
+	ProvidersReasonChecksumMismatch string = "ChecksumMismatch"
+
+	UnsupportedCondition         string = "Unsupported"
+	UnsupportedReasonPodTemplate string = "PodTemplate"
+
+	UnderProvisionedCondition    string = "UnderProvisioned"
+	UnderProvisionedReasonSized  string = "Sized"
+	UnderProvisionedReasonSizing string = "SizingWarnings"
+)
+
+type ExposureMode string
+
+const (
+	ExposureRoute   ExposureMode = "Route"
+	ExposureIngress ExposureMode = "Ingress"
+	ExposureGateway ExposureMode = "Gateway"
+)
+
+type GatewayTLSTermination string
+
+const (
+	// GatewayTLSReencrypt terminates TLS on the gateway and verifies Keycloak through a BackendTLSPolicy
+	GatewayTLSReencrypt GatewayTLSTermination = "Reencrypt"
+	// GatewayTLSPassthrough forwards TLS to Keycloak through a TLSRoute
+	GatewayTLSPassthrough GatewayTLSTermination = "Passthrough"
+)
+
+// +kubebuilder:validation:XValidation:rule="!has(self.exposure) || self.exposure != 'Gateway' || has(self.gateway)",message="gateway is required by the Gateway exposure"
 type NetworkConfig struct {
 	// Enable proxy mode will set
 	// KC_PROXY_HEADERS=xforwarded
@@ -64,11 +390,146 @@ type NetworkConfig struct {
 
 	// Required if Proxy is disabled
 	Hostname string `json:"hostname,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Enum=Route;Ingress;Gateway
+	// How Keycloak is exposed, defaults to Ingress when spec.ingress is set or routes are not available, Route otherwise
+	Exposure ExposureMode `json:"exposure,omitempty"`
+
+	// +optional
+	// Gateway API route settings of the Gateway exposure
+	Gateway *GatewaySpec `json:"gateway,omitempty"`
 }
 
+// +kubebuilder:validation:XValidation:rule="(has(self.tlsTermination) && self.tlsTermination == 'Passthrough') || has(self.backendCACertificate)",message="backendCACertificate is required to reencrypt"
+type GatewaySpec struct {
+	// Gateway the route is attached to
+	ParentRef GatewayParentRef `json:"parentRef"`
+
+	// +optional
+	// +kubebuilder:validation:Enum=Reencrypt;Passthrough
+	// +kubebuilder:default=Reencrypt
+	// Reencrypt renders an HTTPRoute and a BackendTLSPolicy, Passthrough a TLSRoute
+	TLSTermination GatewayTLSTermination `json:"tlsTermination,omitempty"`
+
+	// +optional
+	// ConfigMap holding the CA of the Keycloak certificate under ca.crt, the gateway verifies Keycloak against it.
+	// Required to reencrypt, system CAs don't cover the service CA or private issuers.
+	BackendCACertificate *v1.LocalObjectReference `json:"backendCACertificate,omitempty"`
+}
+
+type GatewayParentRef struct {
+	Name string `json:"name"`
+
+	// +optional
+	// Defaults to the Keycloak namespace
+	Namespace string `json:"namespace,omitempty"`
+
+	// +optional
+	// Listener of the Gateway
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+func (g *GatewaySpec) GetTLSTermination() GatewayTLSTermination {
+	if g.TLSTermination == "" {
+		return GatewayTLSReencrypt
+	}
+
+	return g.TLSTermination
+}
+
+// +kubebuilder:validation:XValidation:rule="[has(self.url) && (has(self.url.value) || has(self.url.secret)), has(self.image), has(self.maven)].filter(x, x).size() == 1",message="exactly one of url, image or maven is required"
+// +kubebuilder:validation:XValidation:rule="!has(self.image) || !has(self.sha256) || !(has(self.sha256.value) || has(self.sha256.secret))",message="sha256 is not supported for image providers"
 type Provider struct {
-	Name string       `json:"name"`
-	URL  SecretOption `json:"url"`
+	Name string `json:"name"`
+
+	// +optional
+	// URL of the JAR, downloaded as name
+	URL SecretOption `json:"url,omitempty"`
+
+	// +optional
+	// Hex encoded SHA-256 checksum the downloaded JAR must match, pods don't start on a mismatch.
+	// Not supported for images, a digest pins their content.
+	SHA256 SecretOption `json:"sha256,omitempty"`
+
+	// +optional
+	// Image holding the JARs, copied as is
+	Image *ImageSource `json:"image,omitempty"`
+
+	// +optional
+	// JAR of a Maven repository, downloaded as name
+	Maven *MavenArtifact `json:"maven,omitempty"`
+}
+
+// ProviderCacheSpec is a volume shared by all pods and import jobs, the operator creates <name>-provider-cache
+// unless claimName is set
+type ProviderCacheSpec struct {
+	// +optional
+	// Existing PersistentVolumeClaim, ReadWriteMany unless all pods run on a single node
+	ClaimName string `json:"claimName,omitempty"`
+
+	// +optional
+	// Size of the created claim, defaults to 1Gi
+	Size *resource.Quantity `json:"size,omitempty"`
+
+	// +optional
+	// Storage class of the created claim, it must support ReadWriteMany
+	StorageClassName *string `json:"storageClassName,omitempty"`
+}
+
+type MavenArtifact struct {
+	// +kubebuilder:validation:Pattern=`^[^:/\s]+:[^:/\s]+:[^:/\s]+(:[^:/\s]+)?$`
+	// groupId:artifactId:version[:classifier] of a released JAR
+	Coordinates string `json:"coordinates"`
+
+	// Name of a repository of spec.mavenRepositories
+	Repository string `json:"repository"`
+}
+
+type MavenRepository struct {
+	// Name referenced by providers
+	Name string `json:"name"`
+
+	// +kubebuilder:validation:Pattern=`^https?://`
+	// Base URL of the repository, e.g. https://nexus.example.com/repository/maven-releases
+	URL string `json:"url"`
+
+	// +optional
+	// Secret with the username and password keys for basic auth
+	CredentialsSecret *v1.LocalObjectReference `json:"credentialsSecret,omitempty"`
+
+	// +optional
+	// Proxy the repository is reached through, e.g. http://proxy.example.com:3128
+	Proxy string `json:"proxy,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="has(self.configMap) != has(self.image)",message="exactly one of configMap or image is required"
+type Theme struct {
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z0-9._-]+$`
+	// Directory of the theme under /opt/keycloak/themes, the theme name selected in realm settings
+	Name string `json:"name"`
+
+	// +optional
+	// ConfigMap holding the files of the theme, items map keys to paths such as login/theme.properties.
+	// Changes of the ConfigMap roll the pods when it is labelled with sso.stakater.com/watched=true.
+	ConfigMap *v1.ConfigMapVolumeSource `json:"configMap,omitempty"`
+
+	// +optional
+	// Image holding the theme directory
+	Image *ImageSource `json:"image,omitempty"`
+}
+
+// ImageSource copies a directory of an image with an init container, the image must provide sh and cp
+type ImageSource struct {
+	// Image reference, a digest or a new tag rolls the pods
+	Image string `json:"image"`
+
+	// +kubebuilder:validation:Pattern=`^/`
+	// Absolute directory in the image whose content is copied
+	Path string `json:"path"`
+
+	// +optional
+	PullPolicy v1.PullPolicy `json:"pullPolicy,omitempty"`
 }
 
 type AdminUser struct {
@@ -77,21 +538,147 @@ type AdminUser struct {
 }
 
 type Features struct {
-	Enabled  []string `json:"enabled,omitempty"`
+	// +optional
+	// Features to enable, a version can be pinned with the name:v1 syntax
+	Enabled []string `json:"enabled,omitempty"`
+
+	// +optional
+	// Features to disable
 	Disabled []string `json:"disabled,omitempty"`
 }
 
-type PostgresDatabase struct {
-	Host     SecretOption `json:"host"`
-	Port     SecretOption `json:"port"`
+type DatabaseVendor string
+
+const (
+	DatabaseVendorPostgres DatabaseVendor = "postgres"
+	DatabaseVendorMariaDB  DatabaseVendor = "mariadb"
+	DatabaseVendorMySQL    DatabaseVendor = "mysql"
+	DatabaseVendorMSSQL    DatabaseVendor = "mssql"
+)
+
+type DatabaseTLSMode string
+
+const (
+	DatabaseTLSModeDisable    DatabaseTLSMode = "disable"
+	DatabaseTLSModeRequire    DatabaseTLSMode = "require"
+	DatabaseTLSModeVerifyCA   DatabaseTLSMode = "verify-ca"
+	DatabaseTLSModeVerifyFull DatabaseTLSMode = "verify-full"
+)
+
+// +kubebuilder:validation:XValidation:rule="has(self.url) || has(self.host)",message="host is required unless url is set"
+// +kubebuilder:validation:XValidation:rule="!has(self.urlProperties) || (!has(self.tls) && !has(self.parameters))",message="urlProperties can't be combined with tls or parameters"
+type Database struct {
+	// +optional
+	// +kubebuilder:validation:Enum=postgres;mariadb;mysql;mssql
+	// +kubebuilder:default=postgres
+	// Database vendor, defaults to postgres
+	Vendor DatabaseVendor `json:"vendor,omitempty"`
+
+	// +optional
+	Host SecretOption `json:"host,omitempty"`
+
+	// +optional
+	// Defaults to the vendor port
+	Port SecretOption `json:"port,omitempty"`
+
 	User     SecretOption `json:"user"`
 	Password SecretOption `json:"password"`
+
+	// +optional
+	// Database name, defaults to keycloak
+	Database *SecretOption `json:"database,omitempty"`
+
+	// +optional
+	// Schema for vendors supporting it
+	Schema string `json:"schema,omitempty"`
+
+	// +optional
+	// Properties appended to the JDBC URL as is, including the vendor separator e.g. ?ssl=true or ;encrypt=true
+	URLProperties string `json:"urlProperties,omitempty"`
+
+	// +optional
+	// Extra JDBC URL parameters, rendered with the vendor separator and taking precedence over the TLS parameters
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// +optional
+	// TLS of the database connection. The CA is added to the Keycloak truststore even if url is set,
+	// the TLS parameters are only applied to urls built from host.
+	TLS *DatabaseTLS `json:"tls,omitempty"`
+
+	// +optional
+	// Full JDBC URL, host, port, database and urlProperties are ignored when set
+	URL *SecretOption `json:"url,omitempty"`
+
+	// +optional
+	// Connection pool of each pod, derived from sizing when not set
+	Pool *DatabasePool `json:"pool,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="!has(self.minSize) || !has(self.maxSize) || self.minSize <= self.maxSize",message="minSize must not exceed maxSize"
+// +kubebuilder:validation:XValidation:rule="!has(self.initialSize) || !has(self.maxSize) || self.initialSize <= self.maxSize",message="initialSize must not exceed maxSize"
+type DatabasePool struct {
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// Connections opened on startup, defaults to minSize
+	InitialSize *int32 `json:"initialSize,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// Connections kept open, defaults to maxSize
+	MinSize *int32 `json:"minSize,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// Upper bound of connections of a pod
+	MaxSize *int32 `json:"maxSize,omitempty"`
+}
+
+// +kubebuilder:validation:XValidation:rule="!has(self.mode) || self.mode in ['disable', 'require'] || has(self.ca)",message="ca is required to verify the server certificate"
+type DatabaseTLS struct {
+	// +optional
+	// +kubebuilder:validation:Enum=disable;require;verify-ca;verify-full
+	// +kubebuilder:default=verify-full
+	// Verification of the server certificate, MSSQL always verifies the hostname when verifying
+	Mode DatabaseTLSMode `json:"mode,omitempty"`
+
+	// +optional
+	// PEM encoded CA of the database server certificate
+	CA *CertificateSource `json:"ca,omitempty"`
 }
 
+// +kubebuilder:validation:XValidation:rule="has(self.secret) != has(self.configMap)",message="exactly one of secret or configMap is required"
+type CertificateSource struct {
+	// +optional
+	Secret *v1.SecretKeySelector `json:"secret,omitempty"`
+
+	// +optional
+	ConfigMap *v1.ConfigMapKeySelector `json:"configMap,omitempty"`
+}
+
+func (t *DatabaseTLS) GetMode() DatabaseTLSMode {
+	if t.Mode == "" {
+		return DatabaseTLSModeVerifyFull
+	}
+
+	return t.Mode
+}
+
+func (d *Database) GetVendor() DatabaseVendor {
+	if d.Vendor == "" {
+		return DatabaseVendorPostgres
+	}
+
+	return d.Vendor
+}
+
+// +kubebuilder:validation:XValidation:rule="[has(self.value), has(self.secret), has(self.configMap)].filter(x, x).size() <= 1",message="only one of value, secret or configMap may be set"
 type SecretOptionVar struct {
-	Name   string                `json:"name"`
-	Value  string                `json:"value,omitempty"`
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+	// Secret must be labelled with sso.stakater.com/watched=true
 	Secret *v1.SecretKeySelector `json:"secret,omitempty"`
+	// ConfigMap must be labelled with sso.stakater.com/watched=true
+	ConfigMap *v1.ConfigMapKeySelector `json:"configMap,omitempty"`
 }
 
 type SecretOption struct {
@@ -99,13 +686,67 @@ type SecretOption struct {
 	Secret *v1.SecretKeySelector `json:"secret,omitempty"`
 }
 
+func (o SecretOption) IsSet() bool {
+	return o.Value != "" || o.Secret != nil
+}
+
+type DisruptionBudgetStatus struct {
+	// Name of the PodDisruptionBudget
+	Name string `json:"name"`
+
+	// Pods evicted at once
+	MaxUnavailable intstr.IntOrString `json:"maxUnavailable"`
+}
+
+type DatabaseStatus struct {
+	// Connection pool size of each pod
+	PoolMaxSize int32 `json:"poolMaxSize,omitempty"`
+
+	// Connections opened by all pods at most
+	MaxConnections int32 `json:"maxConnections,omitempty"`
+}
+
 // KeycloakStatus defines the observed state of Keycloak
 type KeycloakStatus struct {
+	// Image rolled out to all instances
+	Image string `json:"image,omitempty"`
+
+	// RHBK version rolled out to all instances
+	Version string `json:"version,omitempty"`
+
+	// Database connections opened by the instances
+	Database *DatabaseStatus `json:"database,omitempty"`
+
+	// Hashes of settings verified by pre-flight checks
+	Checks VersionedStatus `json:"checks,omitempty"`
+
+	// Features enabled on the configured image
+	Features []string `json:"features,omitempty"`
+
+	// PodDisruptionBudget protecting the instances, not set for a single instance
+	DisruptionBudget *DisruptionBudgetStatus `json:"disruptionBudget,omitempty"`
+
+	// Pods of the StatefulSet
+	Instances int32 `json:"instances,omitempty"`
+
+	// Label selector of the pods, used by the scale subresource
+	Selector string `json:"selector,omitempty"`
+
+	// Sizing of the instance summed over the imports targeting it
+	Sizing *RealmSizing `json:"sizing,omitempty"`
+
+	// Instances, resources and database connections recommended for the sizing
+	Recommendation *SizingRecommendation `json:"recommendation,omitempty"`
+
+	// Resources of the Keycloak container after merging sizing and spec.resources
+	Resources *v1.ResourceRequirements `json:"resources,omitempty"`
+
 	Conditions `json:",inline"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:subresource:scale:specpath=.spec.instances,statuspath=.status.instances,selectorpath=.status.selector
 
 // Keycloak is the Schema for the keycloaks API
 type Keycloak struct {
@@ -17,6 +17,7 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -39,6 +40,14 @@ type KeycloakImportSpec struct {
 	// +optional
 	// Override if realm already exists
 	OverrideIfExists bool `json:"overrideIfExists,omitempty"`
+
+	// +optional
+	// Expected load of the realm, added to the sizing of the Keycloak instance
+	Sizing *RealmSizing `json:"sizing,omitempty"`
+
+	// +optional
+	// Requests and limits of the import job set on top of the Keycloak pod resources, the job builds Keycloak before importing
+	Resources *corev1.ResourceRequirements `json:"resources,omitempty"`
 }
 
 func (ki *KeycloakImportSpec) HasSecretReference(secretName string) bool {
@@ -62,7 +71,10 @@ const (
 
 // KeycloakImportStatus defines the observed state of KeycloakImport
 type KeycloakImportStatus struct {
-	Version    VersionedStatus `json:"version,omitempty"`
+	Version VersionedStatus `json:"version,omitempty"`
+
+	// Resources of the last import job
+	Resources  *corev1.ResourceRequirements `json:"resources,omitempty"`
 	Conditions `json:",inline"`
 }
 
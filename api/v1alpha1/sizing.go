@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	"fmt"
 	"math"
 
 	corev1 "k8s.io/api/core/v1"
@@ -22,26 +23,49 @@ type RealmSizing struct {
 	// Number of cached sessions, defaults to 10000
 	CachedSessions int32 `json:"cachedSessions"`
 
+	// +optional
+	// Number of cached offline sessions, defaults to 10000
+	CachedOfflineSessions int32 `json:"cachedOfflineSessions,omitempty"`
+
 	// +optional
 	// Disable CPU limits to allow unlimited CPU usage
 	DisableCPULimits bool `json:"disableCPULimits,omitempty"`
 }
 
-// CalculateResourceLimits calculates the required resource limits based on the sizing configuration
-// and the number of instances. The total load will be distributed across all instances.
-func (r *RealmSizing) CalculateResourceLimits(instances *int32) corev1.ResourceRequirements {
-	inst := int32(1)
+const (
+	// Connections opened per vCPU of a pod
+	DatabaseConnectionsPerCPU = 10
+	MinDatabasePoolSize       = 10
+	MaxDatabasePoolSize       = 100
+)
+
+const (
+	// Memory of a pod caching the default sessions and offline sessions
+	BaseMemoryMB    = 1250
+	NonHeapMemoryMB = 300
+	// Share of the memory limit used by the heap, the default of the Keycloak container
+	HeapRAMPercentage = 70
+
+	DefaultCachedSessions        = 10000
+	DefaultCachedOfflineSessions = 10000
+	// Distributed caches keep each session on two instances
+	SessionCacheOwners = 2
+	// Estimated heap used by a cached session
+	MemoryPerCachedSessionKB = 25
+)
+
+func getInstances(instances *int32) int32 {
 	if instances != nil && *instances > 0 {
-		inst = *instances
+		return *instances
 	}
 
-	// Base memory calculation
-	baseMemory := int64(1250)   // MB - Base memory including caches
-	nonHeapMemory := int64(300) // MB - Non-heap memory
-	// Memory limit = (base - non-heap) / 0.7
-	memoryLimitFloat := float64(baseMemory-nonHeapMemory) / 0.7
+	return 1
+}
+
+// cpuCores returns the vCPUs needed per pod when the load is distributed across all instances
+func (r *RealmSizing) cpuCores(instances *int32) float64 {
+	inst := getInstances(instances)
 
-	// CPU calculation
 	var cpuCores float64
 
 	// Password logins (distributed across instances)
@@ -59,6 +83,56 @@ func (r *RealmSizing) CalculateResourceLimits(instances *int32) corev1.ResourceR
 		cpuCores += float64(r.RefreshTokenGrantsPerSecond) / (120.0 * float64(inst))
 	}
 
+	return cpuCores
+}
+
+// cachedPerInstance returns the entries of a distributed cache held by each instance
+func cachedPerInstance(entries int32, instances *int32) int32 {
+	inst := getInstances(instances)
+	owners := min(inst, SessionCacheOwners)
+
+	return int32(math.Ceil(float64(entries) * float64(owners) / float64(inst)))
+}
+
+// CachedSessionsPerInstance returns the sessions cached by each instance
+func (r *RealmSizing) CachedSessionsPerInstance(instances *int32) int32 {
+	sessions := r.CachedSessions
+	if sessions <= 0 {
+		sessions = DefaultCachedSessions
+	}
+
+	return cachedPerInstance(sessions, instances)
+}
+
+// CachedOfflineSessionsPerInstance returns the offline sessions cached by each instance
+func (r *RealmSizing) CachedOfflineSessionsPerInstance(instances *int32) int32 {
+	sessions := r.CachedOfflineSessions
+	if sessions <= 0 {
+		sessions = DefaultCachedOfflineSessions
+	}
+
+	return cachedPerInstance(sessions, instances)
+}
+
+// memoryMB returns the memory a pod needs, sessions beyond the defaults add to the base memory
+func (r *RealmSizing) memoryMB(instances *int32) int64 {
+	extra := max(0, r.CachedSessionsPerInstance(instances)-DefaultCachedSessions) +
+		max(0, r.CachedOfflineSessionsPerInstance(instances)-DefaultCachedOfflineSessions)
+
+	return BaseMemoryMB + int64(math.Ceil(float64(extra)*MemoryPerCachedSessionKB/1024))
+}
+
+// CalculateResourceLimits calculates the required resource limits based on the sizing configuration
+// and the number of instances. The total load will be distributed across all instances.
+func (r *RealmSizing) CalculateResourceLimits(instances *int32) corev1.ResourceRequirements {
+	// Memory grows with the sessions cached per instance
+	baseMemory := r.memoryMB(instances)
+	// Memory limit = (base - non-heap) / heap percentage
+	memoryLimitFloat := float64(baseMemory-NonHeapMemoryMB) / (HeapRAMPercentage / 100.0)
+
+	// CPU calculation
+	cpuCores := r.cpuCores(instances)
+
 	// CPU request is the base CPU needed
 	cpuRequest := int64(cpuCores * 1000)
 	// CPU limit includes 150% headroom
@@ -82,6 +156,102 @@ func (r *RealmSizing) CalculateResourceLimits(instances *int32) corev1.ResourceR
 	return requirements
 }
 
+// DatabasePoolSize derives the connection pool size of a pod from the vCPUs it needs, so the
+// connections opened by all instances grow with the load rather than with the instance count.
+func (r *RealmSizing) DatabasePoolSize(instances *int32) int32 {
+	size := int32(math.Ceil(r.cpuCores(instances) * DatabaseConnectionsPerCPU))
+	if size < MinDatabasePoolSize {
+		return MinDatabasePoolSize
+	}
+
+	if size > MaxDatabasePoolSize {
+		return MaxDatabasePoolSize
+	}
+
+	return size
+}
+
+const (
+	// Instances needed to survive the loss of a pod
+	MinHAInstances = 2
+	// Largest pods that fit common nodes, the load is spread across more instances beyond
+	MaxCPUPerPod      = 4
+	MaxMemoryPerPodMB = 8192
+)
+
+// SizingRecommendation is the sizing of an instance computed from the expected load
+type SizingRecommendation struct {
+	// Instances recommended for high availability and the load
+	Instances int32 `json:"instances"`
+
+	// Resources of each pod with the recommended instances
+	Resources corev1.ResourceRequirements `json:"resources"`
+
+	// Connection pool size of each pod
+	DatabasePoolSize int32 `json:"databasePoolSize"`
+
+	// Connections opened by all pods
+	DatabaseConnections int32 `json:"databaseConnections"`
+}
+
+// RecommendInstances returns the fewest instances that are highly available and keep pods within node-sized limits
+func (r *RealmSizing) RecommendInstances() int32 {
+	total := r.cpuCores(nil)
+	instances := max(MinHAInstances, int32(math.Ceil(total/MaxCPUPerPod)))
+
+	// Sessions spread across more instances lower the memory of each pod, until the cache owners hold all of them
+	for r.memoryMB(&instances) > MaxMemoryPerPodMB {
+		next := instances + 1
+		if r.memoryMB(&next) >= r.memoryMB(&instances) {
+			break
+		}
+		instances = next
+	}
+
+	return instances
+}
+
+// Size computes the pod resources and database connections for the load on the given instances
+func (r *RealmSizing) Size(instances *int32) SizingRecommendation {
+	inst := getInstances(instances)
+	poolSize := r.DatabasePoolSize(&inst)
+
+	return SizingRecommendation{
+		Instances:           inst,
+		Resources:           r.CalculateResourceLimits(&inst),
+		DatabasePoolSize:    poolSize,
+		DatabaseConnections: poolSize * inst,
+	}
+}
+
+// Recommend computes the instances, pod resources and database connections for the load
+func (r *RealmSizing) Recommend() SizingRecommendation {
+	instances := r.RecommendInstances()
+	return r.Size(&instances)
+}
+
+// Warnings lists how the load is under-provisioned with the given instances
+func (r *RealmSizing) Warnings(instances *int32) []string {
+	var warnings []string
+
+	inst := getInstances(instances)
+	if inst < MinHAInstances {
+		warnings = append(warnings, fmt.Sprintf("%d instance is not highly available, at least %d are recommended", inst, MinHAInstances))
+	}
+
+	if cpu := r.cpuCores(instances); cpu > MaxCPUPerPod {
+		warnings = append(warnings, fmt.Sprintf("%.1f vCPUs per pod exceed %d vCPUs, %d instances are recommended",
+			cpu, MaxCPUPerPod, r.RecommendInstances()))
+	}
+
+	if memory := r.memoryMB(instances); memory > MaxMemoryPerPodMB {
+		warnings = append(warnings, fmt.Sprintf("%dMi memory per pod exceed %dMi, %d instances are recommended",
+			memory, MaxMemoryPerPodMB, r.RecommendInstances()))
+	}
+
+	return warnings
+}
+
 // SumSizing sums all non-nil RealmSizing values and uses defaultSizing for nil values.
 func SumSizing(sizings []*RealmSizing, defaultSizing *RealmSizing) RealmSizing {
 	var sum RealmSizing
@@ -92,12 +262,14 @@ func SumSizing(sizings []*RealmSizing, defaultSizing *RealmSizing) RealmSizing {
 				sum.ClientCredentialsGrantsPerSecond += defaultSizing.ClientCredentialsGrantsPerSecond
 				sum.RefreshTokenGrantsPerSecond += defaultSizing.RefreshTokenGrantsPerSecond
 				sum.CachedSessions += defaultSizing.CachedSessions
+				sum.CachedOfflineSessions += defaultSizing.CachedOfflineSessions
 			}
 		} else {
 			sum.LoginsPerSecond += s.LoginsPerSecond
 			sum.ClientCredentialsGrantsPerSecond += s.ClientCredentialsGrantsPerSecond
 			sum.RefreshTokenGrantsPerSecond += s.RefreshTokenGrantsPerSecond
 			sum.CachedSessions += s.CachedSessions
+			sum.CachedOfflineSessions += s.CachedOfflineSessions
 		}
 	}
 	return sum
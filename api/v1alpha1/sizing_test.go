@@ -175,6 +175,60 @@ func TestRealmSizing_CalculateResourceLimits(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "cached sessions grow memory",
+			sizing: RealmSizing{
+				LoginsPerSecond: 15,     // 1 vCPU
+				CachedSessions:  100000, // 90000 sessions beyond the default
+			},
+			instances: 1,
+			want: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    *resource.NewMilliQuantity(1000, resource.DecimalSI),
+					corev1.ResourceMemory: *resource.NewQuantity(3448*1024*1024, resource.BinarySI),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    *resource.NewMilliQuantity(2500, resource.DecimalSI),
+					corev1.ResourceMemory: *resource.NewQuantity(4500*1024*1024, resource.BinarySI),
+				},
+			},
+		},
+		{
+			name: "cached sessions distributed across instances",
+			sizing: RealmSizing{
+				LoginsPerSecond:       45,     // 3 vCPUs
+				CachedSessions:        100000, // 66667 per instance
+				CachedOfflineSessions: 40000,  // 26667 per instance
+			},
+			instances: 3,
+			want: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    *resource.NewMilliQuantity(1000, resource.DecimalSI),
+					corev1.ResourceMemory: *resource.NewQuantity(3041*1024*1024, resource.BinarySI),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    *resource.NewMilliQuantity(2500, resource.DecimalSI),
+					corev1.ResourceMemory: *resource.NewQuantity(3920*1024*1024, resource.BinarySI),
+				},
+			},
+		},
+		{
+			name: "default sessions keep base memory",
+			sizing: RealmSizing{
+				CachedSessions: 10000,
+			},
+			instances: 3,
+			want: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    *resource.NewMilliQuantity(0, resource.DecimalSI),
+					corev1.ResourceMemory: *resource.NewQuantity(1250*1024*1024, resource.BinarySI),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    *resource.NewMilliQuantity(0, resource.DecimalSI),
+					corev1.ResourceMemory: *resource.NewQuantity(1360*1024*1024, resource.BinarySI),
+				},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -219,6 +273,65 @@ func TestRealmSizing_CalculateResourceLimits(t *testing.T) {
 	}
 }
 
+func TestRealmSizing_CachedSessionsPerInstance(t *testing.T) {
+	tests := []struct {
+		name        string
+		sizing      RealmSizing
+		instances   int32
+		wantOnline  int32
+		wantOffline int32
+	}{
+		{
+			name:        "defaults",
+			sizing:      RealmSizing{},
+			instances:   1,
+			wantOnline:  DefaultCachedSessions,
+			wantOffline: DefaultCachedOfflineSessions,
+		},
+		{
+			name:        "single instance holds all sessions",
+			sizing:      RealmSizing{CachedSessions: 50000, CachedOfflineSessions: 20000},
+			instances:   1,
+			wantOnline:  50000,
+			wantOffline: 20000,
+		},
+		{
+			name:        "two instances own every session",
+			sizing:      RealmSizing{CachedSessions: 50000, CachedOfflineSessions: 20000},
+			instances:   2,
+			wantOnline:  50000,
+			wantOffline: 20000,
+		},
+		{
+			name:        "sessions spread across four instances",
+			sizing:      RealmSizing{CachedSessions: 50000, CachedOfflineSessions: 20000},
+			instances:   4,
+			wantOnline:  25000,
+			wantOffline: 10000,
+		},
+		{
+			name:        "rounds up",
+			sizing:      RealmSizing{CachedSessions: 10000, CachedOfflineSessions: 10000},
+			instances:   3,
+			wantOnline:  6667,
+			wantOffline: 6667,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := tt.instances
+			if got := tt.sizing.CachedSessionsPerInstance(&inst); got != tt.wantOnline {
+				t.Errorf("CachedSessionsPerInstance() = %v, want %v", got, tt.wantOnline)
+			}
+
+			if got := tt.sizing.CachedOfflineSessionsPerInstance(&inst); got != tt.wantOffline {
+				t.Errorf("CachedOfflineSessionsPerInstance() = %v, want %v", got, tt.wantOffline)
+			}
+		})
+	}
+}
+
 func TestSumSizing(t *testing.T) {
 	defaultSizing := &RealmSizing{
 		LoginsPerSecond:                  10,
@@ -287,3 +400,166 @@ func TestSumSizing(t *testing.T) {
 		})
 	}
 }
+
+func TestRealmSizing_DatabasePoolSize(t *testing.T) {
+	tests := []struct {
+		name      string
+		sizing    RealmSizing
+		instances int32
+		want      int32
+	}{
+		{
+			name: "official example",
+			sizing: RealmSizing{
+				LoginsPerSecond:                  45,  // 3 vCPUs
+				ClientCredentialsGrantsPerSecond: 360, // 3 vCPUs
+				RefreshTokenGrantsPerSecond:      360, // 3 vCPUs
+			},
+			instances: 3,
+			want:      30,
+		},
+		{
+			name: "partial vCPU rounds up",
+			sizing: RealmSizing{
+				LoginsPerSecond: 20, // 1.33 vCPUs
+			},
+			instances: 1,
+			want:      14,
+		},
+		{
+			name:      "idle instance keeps minimum pool",
+			sizing:    RealmSizing{},
+			instances: 2,
+			want:      MinDatabasePoolSize,
+		},
+		{
+			name: "heavy load is capped",
+			sizing: RealmSizing{
+				LoginsPerSecond: 300, // 20 vCPUs
+			},
+			instances: 1,
+			want:      MaxDatabasePoolSize,
+		},
+		{
+			name: "zero instances defaults to one",
+			sizing: RealmSizing{
+				LoginsPerSecond: 30, // 2 vCPUs
+			},
+			instances: 0,
+			want:      20,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := tt.instances
+			if got := tt.sizing.DatabasePoolSize(&inst); got != tt.want {
+				t.Errorf("DatabasePoolSize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRealmSizing_RecommendInstances(t *testing.T) {
+	tests := []struct {
+		name   string
+		sizing RealmSizing
+		want   int32
+	}{
+		{
+			name:   "light load keeps high availability",
+			sizing: RealmSizing{LoginsPerSecond: 5},
+			want:   MinHAInstances,
+		},
+		{
+			name: "heavy load is spread across node-sized pods",
+			sizing: RealmSizing{
+				LoginsPerSecond: 300, // 20 vCPUs
+			},
+			want: 5,
+		},
+		{
+			name: "cached sessions are spread until pods fit",
+			sizing: RealmSizing{
+				CachedSessions: 400000,
+			},
+			want: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sizing.RecommendInstances(); got != tt.want {
+				t.Errorf("RecommendInstances() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRealmSizing_Recommend(t *testing.T) {
+	sizing := RealmSizing{
+		LoginsPerSecond: 90, // 6 vCPUs
+	}
+
+	got := sizing.Recommend()
+	if got.Instances != 2 {
+		t.Errorf("Recommend().Instances = %v, want 2", got.Instances)
+	}
+
+	if cpu := got.Resources.Requests.Cpu().String(); cpu != "3" {
+		t.Errorf("Recommend().Resources cpu request = %v, want 3", cpu)
+	}
+
+	if got.DatabasePoolSize != 30 || got.DatabaseConnections != 60 {
+		t.Errorf("Recommend() database = %v/%v, want 30/60", got.DatabasePoolSize, got.DatabaseConnections)
+	}
+}
+
+func TestRealmSizing_Warnings(t *testing.T) {
+	tests := []struct {
+		name      string
+		sizing    RealmSizing
+		instances int32
+		want      int
+	}{
+		{
+			name:      "single instance is not highly available",
+			sizing:    RealmSizing{LoginsPerSecond: 5},
+			instances: 1,
+			want:      1,
+		},
+		{
+			name: "provisioned instances",
+			sizing: RealmSizing{
+				LoginsPerSecond: 45, // 3 vCPUs
+			},
+			instances: 3,
+			want:      0,
+		},
+		{
+			name: "cpu per pod above node size",
+			sizing: RealmSizing{
+				LoginsPerSecond: 300, // 20 vCPUs
+			},
+			instances: 2,
+			want:      1,
+		},
+		{
+			name: "memory per pod above node size",
+			sizing: RealmSizing{
+				CachedSessions: 400000,
+			},
+			instances: 2,
+			want:      1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inst := tt.instances
+			if got := tt.sizing.Warnings(&inst); len(got) != tt.want {
+				t.Errorf("Warnings() = %v, want %v warnings", got, tt.want)
+			}
+		})
+	}
+}
@@ -47,6 +47,8 @@ import (
 
 	ssov1alpha1 "github.com/stakater/rhbk-operator/api/v1alpha1"
 	"github.com/stakater/rhbk-operator/internal/controller"
+	"github.com/stakater/rhbk-operator/internal/database"
+	"github.com/stakater/rhbk-operator/internal/platform"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -135,11 +137,14 @@ func main() {
 				constants.RHBKWatchedResourceLabel: strconv.FormatBool(true),
 			}
 
-			// Only watch marked secrets
+			// Only watch marked secrets and configmaps
 			opts.ByObject = map[client.Object]cache.ByObject{
 				&v12.Secret{}: {
 					Label: labels.SelectorFromSet(watchEnabledLabel),
 				},
+				&v12.ConfigMap{}: {
+					Label: labels.SelectorFromSet(watchEnabledLabel),
+				},
 			}
 
 			return cache.New(config, opts)
@@ -150,9 +155,21 @@ func main() {
 		os.Exit(1)
 	}
 
+	capabilities, err := platform.Detect(mgr.GetConfig())
+	if err != nil {
+		setupLog.Error(err, "unable to detect platform capabilities")
+		os.Exit(1)
+	}
+	setupLog.Info("detected platform", "route", capabilities.Route, "serviceMonitor", capabilities.ServiceMonitor,
+		"httpRoute", capabilities.HTTPRoute, "tlsRoute", capabilities.TLSRoute, "backendTLSPolicy", capabilities.BackendTLSPolicy,
+		"certManager", capabilities.CertManager)
+
 	if err = (&controller.KeycloakReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		APIReader:       mgr.GetAPIReader(),
+		DatabaseChecker: database.NewConnectionChecker(),
+		Capabilities:    capabilities,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Keycloak")
 		os.Exit(1)
@@ -0,0 +1,243 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// rhbk-sizing computes the resources the operator gives a Keycloak instance for a load,
+// without a cluster. The load is read from flags or from the sizing of a Keycloak manifest.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	ssov1alpha1 "github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources/rhbk"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+)
+
+// Result is the sizing of the configured instances along with the recommendation for the load
+type Result struct {
+	Sizing         ssov1alpha1.RealmSizing          `json:"sizing"`
+	Configured     ssov1alpha1.SizingRecommendation `json:"configured"`
+	Recommendation ssov1alpha1.SizingRecommendation `json:"recommendation"`
+	Warnings       []string                         `json:"warnings,omitempty"`
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, w io.Writer) error {
+	var manifest string
+	var output string
+	var instances int
+	var sizing ssov1alpha1.RealmSizing
+
+	fs := flag.NewFlagSet("rhbk-sizing", flag.ContinueOnError)
+	fs.StringVar(&manifest, "f", "", "Keycloak manifest to read the sizing and instances from, flags override its values.")
+	fs.StringVar(&output, "o", OutputTable, "Output format, one of table or json.")
+	fs.IntVar(&instances, "instances", 1, "Number of Keycloak instances.")
+	fs.Func("logins", "Password logins per second.", int32Flag(&sizing.LoginsPerSecond))
+	fs.Func("client-credentials", "Client credentials grants per second.", int32Flag(&sizing.ClientCredentialsGrantsPerSecond))
+	fs.Func("refresh-tokens", "Refresh token grants per second.", int32Flag(&sizing.RefreshTokenGrantsPerSecond))
+	fs.Func("sessions", "Cached sessions, defaults to 10000.", int32Flag(&sizing.CachedSessions))
+	fs.Func("offline-sessions", "Cached offline sessions, defaults to 10000.", int32Flag(&sizing.CachedOfflineSessions))
+	fs.BoolVar(&sizing.DisableCPULimits, "disable-cpu-limits", false, "Do not set CPU limits.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	flags := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { flags[f.Name] = true })
+
+	kc := &ssov1alpha1.Keycloak{}
+	if manifest != "" {
+		var err error
+		if kc, err = readManifest(manifest); err != nil {
+			return err
+		}
+	}
+
+	// Explicit flags take precedence over the manifest
+	inst := int32(instances)
+	if !flags["instances"] && kc.Spec.GetMinInstances() != nil {
+		inst = *kc.Spec.GetMinInstances()
+	}
+
+	sizing = mergeSizing(kc, sizing, flags)
+	if kc.Spec.Sizing != nil || isLoadSet(flags) {
+		kc.Spec.Sizing = &sizing
+	}
+
+	result := Result{
+		Sizing:         sizing,
+		Configured:     configure(kc, inst),
+		Recommendation: sizing.Recommend(),
+		Warnings:       sizing.Warnings(&inst),
+	}
+
+	switch output {
+	case OutputJSON:
+		return writeJSON(w, result)
+	case OutputTable:
+		return writeTable(w, result)
+	default:
+		return fmt.Errorf("unknown output format %q", output)
+	}
+}
+
+// isLoadSet tells whether a flag of the sizing was set on the command line
+func isLoadSet(flags map[string]bool) bool {
+	for _, name := range []string{"logins", "client-credentials", "refresh-tokens", "sessions", "offline-sessions", "disable-cpu-limits"} {
+		if flags[name] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// configure returns what the operator applies to the pods of the Keycloak on the given instances,
+// including spec.resources, the pool overrides and the defaults of an instance without sizing
+func configure(kc *ssov1alpha1.Keycloak, instances int32) ssov1alpha1.SizingRecommendation {
+	kc = kc.DeepCopy()
+	kc.Spec.Instances = &instances
+	if kc.Spec.Autoscaling != nil {
+		kc.Spec.Autoscaling.MinReplicas = instances
+	}
+
+	ks := &rhbk.RHBKStatefulSet{Keycloak: kc}
+	poolSize := *ks.GetDatabasePool().MaxSize
+
+	return ssov1alpha1.SizingRecommendation{
+		Instances:           instances,
+		Resources:           ks.GetResources(),
+		DatabasePoolSize:    poolSize,
+		DatabaseConnections: poolSize * instances,
+	}
+}
+
+func int32Flag(target *int32) func(string) error {
+	return func(s string) error {
+		var v int32
+		if _, err := fmt.Sscan(s, &v); err != nil {
+			return err
+		}
+
+		*target = v
+		return nil
+	}
+}
+
+func readManifest(path string) (*ssov1alpha1.Keycloak, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest. %w", err)
+	}
+
+	kc := &ssov1alpha1.Keycloak{}
+	if err = yaml.UnmarshalStrict(data, kc); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest. %w", err)
+	}
+
+	return kc, nil
+}
+
+// mergeSizing returns the sizing of the manifest with the values of the flags set on the command line
+func mergeSizing(kc *ssov1alpha1.Keycloak, flags ssov1alpha1.RealmSizing, set map[string]bool) ssov1alpha1.RealmSizing {
+	var sizing ssov1alpha1.RealmSizing
+	if kc.Spec.Sizing != nil {
+		sizing = *kc.Spec.Sizing
+	}
+
+	if set["logins"] {
+		sizing.LoginsPerSecond = flags.LoginsPerSecond
+	}
+	if set["client-credentials"] {
+		sizing.ClientCredentialsGrantsPerSecond = flags.ClientCredentialsGrantsPerSecond
+	}
+	if set["refresh-tokens"] {
+		sizing.RefreshTokenGrantsPerSecond = flags.RefreshTokenGrantsPerSecond
+	}
+	if set["sessions"] {
+		sizing.CachedSessions = flags.CachedSessions
+	}
+	if set["offline-sessions"] {
+		sizing.CachedOfflineSessions = flags.CachedOfflineSessions
+	}
+	if set["disable-cpu-limits"] {
+		sizing.DisableCPULimits = flags.DisableCPULimits
+	}
+
+	return sizing
+}
+
+func writeJSON(w io.Writer, result Result) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+func writeTable(w io.Writer, result Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\tINSTANCES\tCPU REQUEST\tCPU LIMIT\tMEMORY REQUEST\tMEMORY LIMIT\tDB POOL\tDB CONNECTIONS")
+	for _, row := range []struct {
+		name   string
+		sizing ssov1alpha1.SizingRecommendation
+	}{
+		{"configured", result.Configured},
+		{"recommended", result.Recommendation},
+	} {
+		cpuLimit := "-"
+		if limit, ok := row.sizing.Resources.Limits[corev1.ResourceCPU]; ok {
+			cpuLimit = limit.String()
+		}
+
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%d\t%d\n",
+			row.name,
+			row.sizing.Instances,
+			row.sizing.Resources.Requests.Cpu(),
+			cpuLimit,
+			row.sizing.Resources.Requests.Memory(),
+			row.sizing.Resources.Limits.Memory(),
+			row.sizing.DatabasePoolSize,
+			row.sizing.DatabaseConnections,
+		)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, warning := range result.Warnings {
+		fmt.Fprintf(w, "WARNING: %s\n", warning)
+	}
+
+	return nil
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	ssov1alpha1 "github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources/rhbk"
+)
+
+const manifest = `apiVersion: sso.stakater.com/v1alpha1
+kind: Keycloak
+metadata:
+  name: rhbk
+spec:
+  instances: 3
+  sizing:
+    loginsPerSecond: 45
+    clientCredentialsGrantsPerSecond: 360
+    refreshTokenGrantsPerSecond: 360
+  resources:
+    limits:
+      memory: 2Gi
+  database:
+    pool:
+      maxSize: 50
+`
+
+func TestInt32Flag(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    int32
+		wantErr bool
+	}{
+		{
+			name:  "number",
+			value: "45",
+			want:  45,
+		},
+		{
+			name:    "not a number",
+			value:   "many",
+			wantErr: true,
+		},
+		{
+			name:    "overflow",
+			value:   "3000000000",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got int32
+			err := int32Flag(&got)(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("int32Flag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("int32Flag() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeSizing(t *testing.T) {
+	manifestSizing := &ssov1alpha1.RealmSizing{
+		LoginsPerSecond:             45,
+		RefreshTokenGrantsPerSecond: 360,
+		CachedSessions:              50000,
+	}
+
+	tests := []struct {
+		name   string
+		sizing *ssov1alpha1.RealmSizing
+		flags  ssov1alpha1.RealmSizing
+		set    map[string]bool
+		want   ssov1alpha1.RealmSizing
+	}{
+		{
+			name:   "manifest only",
+			sizing: manifestSizing,
+			want:   *manifestSizing,
+		},
+		{
+			name:  "flags only",
+			flags: ssov1alpha1.RealmSizing{LoginsPerSecond: 30, DisableCPULimits: true},
+			set:   map[string]bool{"logins": true, "disable-cpu-limits": true},
+			want:  ssov1alpha1.RealmSizing{LoginsPerSecond: 30, DisableCPULimits: true},
+		},
+		{
+			name:   "flags override manifest",
+			sizing: manifestSizing,
+			flags:  ssov1alpha1.RealmSizing{LoginsPerSecond: 30, CachedSessions: 0, CachedOfflineSessions: 20000},
+			set:    map[string]bool{"logins": true, "sessions": true, "offline-sessions": true},
+			want: ssov1alpha1.RealmSizing{
+				LoginsPerSecond:             30,
+				RefreshTokenGrantsPerSecond: 360,
+				CachedOfflineSessions:       20000,
+			},
+		},
+		{
+			name:   "unset flags keep manifest",
+			sizing: manifestSizing,
+			flags:  ssov1alpha1.RealmSizing{RefreshTokenGrantsPerSecond: 1, ClientCredentialsGrantsPerSecond: 1},
+			set:    map[string]bool{"client-credentials": true},
+			want: ssov1alpha1.RealmSizing{
+				LoginsPerSecond:                  45,
+				ClientCredentialsGrantsPerSecond: 1,
+				RefreshTokenGrantsPerSecond:      360,
+				CachedSessions:                   50000,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kc := &ssov1alpha1.Keycloak{}
+			kc.Spec.Sizing = tt.sizing
+
+			if got := mergeSizing(kc, tt.flags, tt.set); got != tt.want {
+				t.Errorf("mergeSizing() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	sizing := &ssov1alpha1.RealmSizing{
+		LoginsPerSecond:                  45,  // 3 vCPUs
+		ClientCredentialsGrantsPerSecond: 360, // 3 vCPUs
+		RefreshTokenGrantsPerSecond:      360, // 3 vCPUs
+	}
+	maxSize := int32(50)
+
+	tests := []struct {
+		name      string
+		spec      ssov1alpha1.KeycloakSpec
+		instances int32
+		want      ssov1alpha1.SizingRecommendation
+	}{
+		{
+			name:      "unsized",
+			instances: 2,
+			want: ssov1alpha1.SizingRecommendation{
+				Instances: 2,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1700Mi")},
+					Limits:   corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+				},
+				DatabasePoolSize:    rhbk.DefaultDatabasePoolSize,
+				DatabaseConnections: 2 * rhbk.DefaultDatabasePoolSize,
+			},
+		},
+		{
+			name:      "sized",
+			spec:      ssov1alpha1.KeycloakSpec{Sizing: sizing},
+			instances: 3,
+			want:      sizing.Size(&[]int32{3}[0]),
+		},
+		{
+			name: "resources and pool overrides",
+			spec: ssov1alpha1.KeycloakSpec{
+				Sizing: sizing,
+				Resources: &corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("2Gi")},
+				},
+				Database: &ssov1alpha1.Database{Pool: &ssov1alpha1.DatabasePool{MaxSize: &maxSize}},
+			},
+			instances: 3,
+			want: ssov1alpha1.SizingRecommendation{
+				Instances: 3,
+				Resources: corev1.ResourceRequirements{
+					Requests: corev1.ResourceList{
+						corev1.ResourceCPU:    *resource.NewMilliQuantity(3000, resource.DecimalSI),
+						corev1.ResourceMemory: *resource.NewQuantity(1250*1024*1024, resource.BinarySI),
+					},
+					Limits: corev1.ResourceList{
+						corev1.ResourceCPU:    *resource.NewMilliQuantity(7500, resource.DecimalSI),
+						corev1.ResourceMemory: resource.MustParse("2Gi"),
+					},
+				},
+				DatabasePoolSize:    50,
+				DatabaseConnections: 150,
+			},
+		},
+		{
+			name: "autoscaling",
+			spec: ssov1alpha1.KeycloakSpec{
+				Sizing:      sizing,
+				Autoscaling: &ssov1alpha1.AutoscalingSpec{MinReplicas: 1, MaxReplicas: 5},
+			},
+			instances: 3,
+			want:      sizing.Size(&[]int32{3}[0]),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := configure(&ssov1alpha1.Keycloak{Spec: tt.spec}, tt.instances)
+			if got.Instances != tt.want.Instances || got.DatabasePoolSize != tt.want.DatabasePoolSize || got.DatabaseConnections != tt.want.DatabaseConnections {
+				t.Errorf("configure() = %+v, want %+v", got, tt.want)
+			}
+
+			if !equalResourceLists(got.Resources.Requests, tt.want.Resources.Requests) || !equalResourceLists(got.Resources.Limits, tt.want.Resources.Limits) {
+				t.Errorf("configure() resources = %v, want %v", got.Resources, tt.want.Resources)
+			}
+		})
+	}
+}
+
+func TestRun(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keycloak.yaml")
+	if err := os.WriteFile(path, []byte(manifest), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "flags",
+			args: []string{"-logins", "45", "-client-credentials", "360", "-refresh-tokens", "360", "-instances", "3"},
+			want: []string{
+				"configured   3          3            7500m      1250Mi          1360Mi        30       90",
+				"recommended  3          3            7500m      1250Mi          1360Mi        30       90",
+			},
+		},
+		{
+			name: "single instance warns",
+			args: []string{"-logins", "15"},
+			want: []string{"WARNING: 1 instance is not highly available, at least 2 are recommended"},
+		},
+		{
+			name: "manifest",
+			args: []string{"-f", path},
+			want: []string{"configured   3          3            7500m      1250Mi          2Gi           50       150"},
+		},
+		{
+			name: "flags override manifest",
+			args: []string{"-f", path, "-instances", "1", "-disable-cpu-limits"},
+			want: []string{"configured   1          9            -          1250Mi          2Gi           50       50"},
+		},
+		{
+			name:    "unknown output",
+			args:    []string{"-o", "yaml"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid flag",
+			args:    []string{"-logins", "many"},
+			wantErr: true,
+		},
+		{
+			name:    "missing manifest",
+			args:    []string{"-f", filepath.Join(t.TempDir(), "missing.yaml")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			err := run(tt.args, out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			for _, line := range tt.want {
+				if !strings.Contains(out.String(), line+"\n") {
+					t.Errorf("run() output misses %q\n%s", line, out)
+				}
+			}
+		})
+	}
+}
+
+func TestRunJSON(t *testing.T) {
+	out := &bytes.Buffer{}
+	if err := run([]string{"-logins", "45", "-instances", "3", "-o", "json"}, out); err != nil {
+		t.Fatal(err)
+	}
+
+	var got Result
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON output. %v\n%s", err, out)
+	}
+
+	sizing := ssov1alpha1.RealmSizing{LoginsPerSecond: 45}
+	want := Result{
+		Sizing:         sizing,
+		Configured:     sizing.Size(&[]int32{3}[0]),
+		Recommendation: sizing.Recommend(),
+	}
+
+	if got.Sizing != want.Sizing || got.Configured.DatabasePoolSize != want.Configured.DatabasePoolSize ||
+		got.Recommendation.Instances != want.Recommendation.Instances || len(got.Warnings) != 0 {
+		t.Errorf("run() = %+v, want %+v", got, want)
+	}
+
+	if !equalResourceLists(got.Configured.Resources.Limits, want.Configured.Resources.Limits) {
+		t.Errorf("run() configured limits = %v, want %v", got.Configured.Resources.Limits, want.Configured.Resources.Limits)
+	}
+}
+
+func equalResourceLists(a, b corev1.ResourceList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for name, quantity := range a {
+		other, ok := b[name]
+		if !ok || quantity.Cmp(other) != 0 {
+			return false
+		}
+	}
+
+	return true
+}
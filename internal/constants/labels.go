@@ -3,3 +3,6 @@ package constants
 const RHBKWatchedResourceLabel = "sso.stakater.com/watched"
 const RHBKImportOwnerLabel = "realm.stakater.com/owner"
 const RHBKImportNamespaceLabel = "realm.stakater.com/namepsace"
+
+// RHBKInstanceLabel tells apart the pods of Keycloak instances sharing a namespace
+const RHBKInstanceLabel = "sso.stakater.com/instance"
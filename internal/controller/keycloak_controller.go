@@ -19,11 +19,17 @@ package controller
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	v12 "github.com/openshift/api/route/v1"
 	v15 "github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1"
 	v13 "k8s.io/api/apps/v1"
+	v19 "k8s.io/api/autoscaling/v2"
+	v16 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	v17 "k8s.io/api/networking/v1"
+	v18 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	v14 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -31,26 +37,53 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	ssov1alpha1 "github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/constants"
+	"github.com/stakater/rhbk-operator/internal/database"
+	"github.com/stakater/rhbk-operator/internal/platform"
 	"github.com/stakater/rhbk-operator/internal/resources"
 	"github.com/stakater/rhbk-operator/internal/resources/monitoring"
+	"github.com/stakater/rhbk-operator/internal/resources/realm"
 	"github.com/stakater/rhbk-operator/internal/resources/rhbk"
 )
 
+const UpgradeRequeueInterval = 10 * time.Second
+const DatabaseRequeueInterval = 30 * time.Second
+
 type KeycloakReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+	// Reads secrets outside the manager cache
+	APIReader client.Reader
+	// Pre-flight database check, skipped when nil
+	DatabaseChecker database.Checker
+	// Optional APIs of the cluster
+	Capabilities platform.Capabilities
 }
 
 //+kubebuilder:rbac:groups=sso.stakater.com,resources=keycloaks,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=sso.stakater.com,resources=keycloaks/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=sso.stakater.com,resources=keycloaks/finalizers,verbs=update
+//+kubebuilder:rbac:groups=sso.stakater.com,resources=keycloakimports,verbs=get;list;watch
 //+kubebuilder:rbac:groups=apps,resources=statefulsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;delete
 //+kubebuilder:rbac:groups=core,resources=services,verbs=get;list;watch;create;update
-//+kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update
+//+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch
+//+kubebuilder:rbac:groups=core,resources=pods,verbs=get;list
+//+kubebuilder:rbac:groups=core,resources=persistentvolumeclaims,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=core,resources=secrets,verbs=get
+//+kubebuilder:rbac:groups=route.openshift.io,resources=routes,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=httproutes;tlsroutes;backendtlspolicies,verbs=get;list;watch;create;update;delete
+//+kubebuilder:rbac:groups=gateway.networking.k8s.io,resources=gateways,verbs=get;list;watch
+//+kubebuilder:rbac:groups=cert-manager.io,resources=certificates,verbs=get;list;watch;create;update;delete
 // +kubebuilder:rbac:groups=route.openshift.io,resources=routes/custom-host,verbs=get;create;update;delete
 // +kubebuilder:rbac:groups=monitoring.coreos.com,resources=servicemonitors,verbs=get;list;create;update;delete;watch
 
@@ -77,26 +110,116 @@ func (r *KeycloakReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return r.HandleError(ctx, cr, err, "Service setup not ready")
 	}
 
-	routeResource := rhbk.RHBKRoute{
-		Keycloak: cr,
-		Scheme:   r.Scheme,
+	hostname, err := r.reconcileExposure(ctx, cr)
+	if err != nil {
+		return r.HandleError(ctx, cr, err, "Exposure setup not ready")
+	}
+
+	err = r.reconcileTLS(ctx, cr, hostname)
+	if err != nil {
+		return r.HandleError(ctx, cr, err, "TLS setup not ready")
 	}
 
-	err = routeResource.CreateOrUpdate(ctx, r.Client)
+	importSizing, err := r.getImportSizing(ctx, cr)
 	if err != nil {
-		return r.HandleError(ctx, cr, err, "route setup not ready")
+		return r.HandleError(ctx, cr, err, "Failed to fetch realm imports")
+	}
+
+	var themesHash string
+	if r.APIReader != nil {
+		themesHash, err = rhbk.GetThemesHash(ctx, r.APIReader, cr)
+		if err != nil {
+			return r.HandleError(ctx, cr, err, "Themes setup not ready")
+		}
 	}
 
 	statefulSetResource := &rhbk.RHBKStatefulSet{
-		Keycloak: cr,
-		HostName: routeResource.Resource.Spec.Host,
-		Scheme:   r.Scheme,
+		Keycloak:   cr,
+		HostName:   hostname,
+		Scheme:     r.Scheme,
+		Sizing:     importSizing,
+		ThemesHash: themesHash,
 	}
+
+	err = rhbk.ValidateFeatures(cr)
+	if err != nil {
+		return r.HandleError(ctx, cr, err, "Features setup not ready")
+	}
+
+	err = realm.ValidateProviders(cr)
+	if err != nil {
+		return r.HandleError(ctx, cr, err, "Providers setup not ready")
+	}
+
+	err = r.reconcileProviderCache(ctx, cr)
+	if err != nil {
+		return r.HandleError(ctx, cr, err, "Provider cache setup not ready")
+	}
+
+	if r.APIReader != nil {
+		err = rhbk.ValidateDatabaseCA(ctx, r.APIReader, cr)
+		if err != nil {
+			return r.HandleError(ctx, cr, err, "Database TLS setup not ready")
+		}
+	}
+
+	err = r.reconcileDatabase(ctx, cr)
+	if err != nil {
+		return r.HandleDatabaseError(ctx, cr, err)
+	}
+
+	current := &v13.StatefulSet{}
+	err = r.Get(ctx, client.ObjectKey{
+		Name:      rhbk.GetStatefulSetName(cr),
+		Namespace: cr.Namespace,
+	}, current)
+	if client.IgnoreNotFound(err) != nil {
+		return r.HandleError(ctx, cr, err, "Deployment setup not ready")
+	}
+
+	upgrading := false
+	if err == nil {
+		var apply bool
+		upgrading, apply, err = r.reconcileUpgrade(ctx, cr, current, statefulSetResource)
+		if err != nil {
+			return r.HandleError(ctx, cr, err, "Upgrade setup not ready")
+		}
+
+		if !apply {
+			return r.HandleUpgrade(ctx, cr)
+		}
+	}
+
 	err = statefulSetResource.CreateOrUpdate(ctx, r.Client)
 	if err != nil {
 		return r.HandleError(ctx, cr, err, "Deployment setup not ready")
 	}
 
+	cr.Status.Features = rhbk.GetEffectiveFeatures(cr)
+	cr.Status.Instances = statefulSetResource.Resource.Status.Replicas
+	cr.Status.Selector = rhbk.GetPodSelector(cr)
+	cr.Status.Sizing = statefulSetResource.GetSizing()
+	cr.Status.Resources = statefulSetResource.Resource.Spec.Template.Spec.Containers[0].Resources.DeepCopy()
+	r.updateRecommendation(cr)
+
+	err = r.updateProviderVerification(ctx, cr)
+	if err != nil {
+		return r.HandleError(ctx, cr, err, "Failed to fetch pods")
+	}
+
+	if cr.Spec.GetPodTemplate() != nil {
+		cr.Status.UpdateCondition(ssov1alpha1.UnsupportedCondition, v14.ConditionTrue, ssov1alpha1.UnsupportedReasonPodTemplate,
+			"spec.unsupported.podTemplate is merged into the pods, the result is not validated by the operator")
+	} else {
+		cr.Status.RemoveCondition(ssov1alpha1.UnsupportedCondition)
+	}
+
+	pool := statefulSetResource.GetDatabasePool()
+	cr.Status.Database = &ssov1alpha1.DatabaseStatus{
+		PoolMaxSize:    *pool.MaxSize,
+		MaxConnections: *pool.MaxSize * cr.Spec.GetMaxInstances(),
+	}
+
 	discoveryServiceResource := rhbk.RHBKDiscoveryService{
 		Keycloak: cr,
 		Scheme:   r.Scheme,
@@ -106,19 +229,186 @@ func (r *KeycloakReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return r.HandleError(ctx, cr, err, "Discovery service setup not ready")
 	}
 
-	serviceMonitorResource := monitoring.NewServiceMonitor(cr, r.Scheme)
-	err = serviceMonitorResource.CreateOrUpdate(ctx, r.Client)
+	err = r.reconcileDisruptionBudget(ctx, cr)
 	if err != nil {
-		return r.HandleError(ctx, cr, err, "Service monitor setup not ready")
+		return r.HandleError(ctx, cr, err, "Disruption budget setup not ready")
+	}
+
+	err = r.reconcileAutoscaler(ctx, cr)
+	if err != nil {
+		return r.HandleError(ctx, cr, err, "Autoscaler setup not ready")
+	}
+
+	if r.Capabilities.ServiceMonitor {
+		serviceMonitorResource := monitoring.NewServiceMonitor(cr, r.Scheme)
+		err = serviceMonitorResource.CreateOrUpdate(ctx, r.Client)
+		if err != nil {
+			return r.HandleError(ctx, cr, err, "Service monitor setup not ready")
+		}
+	}
+
+	if upgrading {
+		return r.HandleUpgrade(ctx, cr)
 	}
 
 	if resources.IsStatefulSetReady(statefulSetResource.Resource) {
+		r.updateRunningVersion(cr, statefulSetResource.Resource)
 		return r.HandleSuccess(ctx, cr)
 	} else {
 		return r.HandleError(ctx, cr, nil, "Waiting for resources to be ready")
 	}
 }
 
+// updateRecommendation publishes the sizing recommended for the load and warns when the instances are under-provisioned
+func (r *KeycloakReconciler) updateRecommendation(cr *ssov1alpha1.Keycloak) {
+	if cr.Status.Sizing == nil {
+		cr.Status.Recommendation = nil
+		cr.Status.RemoveCondition(ssov1alpha1.UnderProvisionedCondition)
+		return
+	}
+
+	recommendation := cr.Status.Sizing.Recommend()
+	cr.Status.Recommendation = &recommendation
+
+	warnings := cr.Status.Sizing.Warnings(cr.Spec.GetMinInstances())
+	if len(warnings) == 0 {
+		cr.Status.UpdateCondition(ssov1alpha1.UnderProvisionedCondition, v14.ConditionFalse, ssov1alpha1.UnderProvisionedReasonSized)
+		return
+	}
+
+	cr.Status.UpdateCondition(ssov1alpha1.UnderProvisionedCondition, v14.ConditionTrue,
+		ssov1alpha1.UnderProvisionedReasonSizing, strings.Join(warnings, "; "))
+}
+
+// updateProviderVerification reports providers failing checksum verification from the termination message of the pods
+func (r *KeycloakReconciler) updateProviderVerification(ctx context.Context, cr *ssov1alpha1.Keycloak) error {
+	verified := false
+	for _, p := range cr.Spec.Providers {
+		verified = verified || p.SHA256.IsSet()
+	}
+
+	if !verified || r.APIReader == nil {
+		cr.Status.RemoveCondition(ssov1alpha1.ProvidersVerifiedCondition)
+		return nil
+	}
+
+	// Pods are read uncached, the manager would otherwise cache every pod of the cluster
+	pods := &v1.PodList{}
+	err := r.APIReader.List(ctx, pods, client.InNamespace(cr.Namespace), client.MatchingLabels{constants.RHBKInstanceLabel: cr.Name})
+	if err != nil {
+		return err
+	}
+
+	if msg := realm.GetChecksumMismatch(pods.Items); msg != "" {
+		cr.Status.UpdateCondition(ssov1alpha1.ProvidersVerifiedCondition, v14.ConditionFalse, ssov1alpha1.ProvidersReasonChecksumMismatch, msg)
+	} else {
+		cr.Status.UpdateCondition(ssov1alpha1.ProvidersVerifiedCondition, v14.ConditionTrue, ssov1alpha1.ProvidersReasonVerified)
+	}
+
+	return nil
+}
+
+// updateRunningVersion records the image once every pod of the StatefulSet runs it
+func (r *KeycloakReconciler) updateRunningVersion(cr *ssov1alpha1.Keycloak, sts *v13.StatefulSet) {
+	if !resources.IsStatefulSetRolledOut(sts) {
+		return
+	}
+
+	cr.Status.Image = rhbk.GetContainerImage(sts)
+	cr.Status.Version = rhbk.GetVersion(cr.Status.Image)
+}
+
+// getImportSizing returns the sizing of the imports targeting the instance, nil for imports declaring none
+func (r *KeycloakReconciler) getImportSizing(ctx context.Context, cr *ssov1alpha1.Keycloak) ([]*ssov1alpha1.RealmSizing, error) {
+	imports := &ssov1alpha1.KeycloakImportList{}
+	err := r.List(ctx, imports)
+	if err != nil {
+		return nil, err
+	}
+
+	var sizing []*ssov1alpha1.RealmSizing
+	for _, kci := range imports.Items {
+		if kci.Spec.KeycloakInstance.Name == cr.Name && kci.Spec.KeycloakInstance.Namespace == cr.Namespace &&
+			kci.DeletionTimestamp == nil {
+			sizing = append(sizing, kci.Spec.Sizing)
+		}
+	}
+
+	return sizing, nil
+}
+
+// reconcileProviderCache creates the claim of the provider cache unless an existing claim is referenced
+func (r *KeycloakReconciler) reconcileProviderCache(ctx context.Context, cr *ssov1alpha1.Keycloak) error {
+	if !rhbk.IsProviderCacheManaged(cr) {
+		// The referenced claim may be named like the created one, it is not owned by the instance
+		if rhbk.GetProviderCacheClaimName(cr) == rhbk.GetProviderCacheName(cr) {
+			return nil
+		}
+
+		return r.deleteIfOwned(ctx, cr, &v1.PersistentVolumeClaim{
+			ObjectMeta: v14.ObjectMeta{
+				Name:      rhbk.GetProviderCacheName(cr),
+				Namespace: cr.Namespace,
+			},
+		})
+	}
+
+	cacheResource := rhbk.RHBKProviderCache{
+		Keycloak: cr,
+		Scheme:   r.Scheme,
+	}
+
+	return cacheResource.CreateOrUpdate(ctx, r.Client)
+}
+
+// reconcileDisruptionBudget protects instances with more than one pod from losing all pods to evictions
+func (r *KeycloakReconciler) reconcileDisruptionBudget(ctx context.Context, cr *ssov1alpha1.Keycloak) error {
+	if !rhbk.IsPodDisruptionBudgetRequired(cr) {
+		cr.Status.DisruptionBudget = nil
+		return r.deleteIfOwned(ctx, cr, &v18.PodDisruptionBudget{
+			ObjectMeta: v14.ObjectMeta{
+				Name:      rhbk.GetPodDisruptionBudgetName(cr),
+				Namespace: cr.Namespace,
+			},
+		})
+	}
+
+	pdbResource := rhbk.RHBKPodDisruptionBudget{
+		Keycloak: cr,
+		Scheme:   r.Scheme,
+	}
+	err := pdbResource.CreateOrUpdate(ctx, r.Client)
+	if err != nil {
+		return err
+	}
+
+	cr.Status.DisruptionBudget = &ssov1alpha1.DisruptionBudgetStatus{
+		Name:           pdbResource.Resource.Name,
+		MaxUnavailable: *pdbResource.Resource.Spec.MaxUnavailable,
+	}
+
+	return nil
+}
+
+// reconcileAutoscaler scales the StatefulSet while autoscaling is on
+func (r *KeycloakReconciler) reconcileAutoscaler(ctx context.Context, cr *ssov1alpha1.Keycloak) error {
+	if cr.Spec.Autoscaling == nil {
+		return r.deleteIfOwned(ctx, cr, &v19.HorizontalPodAutoscaler{
+			ObjectMeta: v14.ObjectMeta{
+				Name:      rhbk.GetHorizontalPodAutoscalerName(cr),
+				Namespace: cr.Namespace,
+			},
+		})
+	}
+
+	hpaResource := rhbk.RHBKHorizontalPodAutoscaler{
+		Keycloak: cr,
+		Scheme:   r.Scheme,
+	}
+
+	return hpaResource.CreateOrUpdate(ctx, r.Client)
+}
+
 func (r *KeycloakReconciler) HandleError(ctx context.Context, cr *ssov1alpha1.Keycloak, err error, msg string) (ctrl.Result, error) {
 	if err != nil {
 		cr.Status.Conditions.SetReady(v14.ConditionFalse, fmt.Sprintf("%s. %s", msg, err.Error()))
@@ -128,6 +418,54 @@ func (r *KeycloakReconciler) HandleError(ctx context.Context, cr *ssov1alpha1.Ke
 	return ctrl.Result{}, r.Status().Update(ctx, cr)
 }
 
+// HandleUpgrade keeps polling while pods are being replaced, StatefulSet events alone don't cover scaling down
+func (r *KeycloakReconciler) HandleUpgrade(ctx context.Context, cr *ssov1alpha1.Keycloak) (ctrl.Result, error) {
+	result, err := r.HandleError(ctx, cr, nil, cr.Status.Conditions.ConditionMsg(ssov1alpha1.UpgradeCondition))
+	result.RequeueAfter = UpgradeRequeueInterval
+	return result, err
+}
+
+// HandleDatabaseError retries the pre-flight check, fixing credentials doesn't trigger a reconcile
+func (r *KeycloakReconciler) HandleDatabaseError(ctx context.Context, cr *ssov1alpha1.Keycloak, err error) (ctrl.Result, error) {
+	result, updateErr := r.HandleError(ctx, cr, err, "Database not ready")
+	result.RequeueAfter = DatabaseRequeueInterval
+	return result, updateErr
+}
+
+// handleImportChanged resizes the instance targeted by an import
+func (r *KeycloakReconciler) handleImportChanged(_ context.Context, object client.Object) []reconcile.Request {
+	kci := object.(*ssov1alpha1.KeycloakImport)
+
+	return []reconcile.Request{
+		{
+			NamespacedName: client.ObjectKey{
+				Namespace: kci.Spec.KeycloakInstance.Namespace,
+				Name:      kci.Spec.KeycloakInstance.Name,
+			},
+		},
+	}
+}
+
+// handleThemeChanged rolls the instances mounting a theme from the ConfigMap, only ConfigMaps labelled with
+// sso.stakater.com/watched=true are seen by the manager cache
+func (r *KeycloakReconciler) handleThemeChanged(ctx context.Context, object client.Object) []reconcile.Request {
+	list := &ssov1alpha1.KeycloakList{}
+	err := r.List(ctx, list, client.InNamespace(object.GetNamespace()))
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list keycloaks for theme configmap", "configmap", object.GetName())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, kc := range list.Items {
+		if rhbk.IsThemeConfigMap(&kc, object) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&kc)})
+		}
+	}
+
+	return requests
+}
+
 func (r *KeycloakReconciler) HandleSuccess(ctx context.Context, cr *ssov1alpha1.Keycloak) (ctrl.Result, error) {
 	cr.Status.Conditions.SetReady(v14.ConditionTrue)
 	return ctrl.Result{}, r.Status().Update(ctx, cr)
@@ -135,11 +473,52 @@ func (r *KeycloakReconciler) HandleSuccess(ctx context.Context, cr *ssov1alpha1.
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *KeycloakReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	b := ctrl.NewControllerManagedBy(mgr)
+	if r.Capabilities.Route {
+		b = b.Owns(&v12.Route{}, builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+
+	if r.Capabilities.ServiceMonitor {
+		b = b.Owns(&v15.ServiceMonitor{}, builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+
+	if r.Capabilities.HTTPRoute {
+		b = b.Owns(rhbk.NewUnstructured(rhbk.HTTPRouteGVK, "", ""), builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+
+	if r.Capabilities.TLSRoute {
+		b = b.Owns(rhbk.NewUnstructured(rhbk.TLSRouteGVK, "", ""), builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+
+	if r.Capabilities.CertManager {
+		b = b.Owns(rhbk.NewUnstructured(rhbk.CertificateGVK, "", ""), builder.WithPredicates(predicate.GenerationChangedPredicate{}))
+	}
+
+	return b.
 		For(&ssov1alpha1.Keycloak{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&ssov1alpha1.KeycloakImport{}, handler.EnqueueRequestsFromMapFunc(r.handleImportChanged),
+			builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&v1.ConfigMap{}, handler.EnqueueRequestsFromMapFunc(r.handleThemeChanged),
+			builder.WithPredicates(predicate.ResourceVersionChangedPredicate{})).
 		Owns(&v1.Service{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
-		Owns(&v12.Route{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
-		Owns(&v15.ServiceMonitor{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&v17.Ingress{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&v18.PodDisruptionBudget{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&v19.HorizontalPodAutoscaler{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Owns(&v16.Job{}, builder.WithPredicates(predicate.Funcs{
+			CreateFunc: func(e event.TypedCreateEvent[client.Object]) bool {
+				return false
+			},
+			DeleteFunc: func(e event.TypedDeleteEvent[client.Object]) bool {
+				return false
+			},
+			UpdateFunc: func(e event.TypedUpdateEvent[client.Object]) bool {
+				old := e.ObjectOld.(*v16.Job)
+				current := e.ObjectNew.(*v16.Job)
+
+				return (!resources.IsJobCompleted(old) && resources.IsJobCompleted(current)) ||
+					(!resources.IsJobFailed(old) && resources.IsJobFailed(current))
+			},
+		})).
 		Owns(&v13.StatefulSet{}, builder.WithPredicates(predicate.Funcs{
 			CreateFunc: func(e event.TypedCreateEvent[client.Object]) bool {
 				return false
@@ -151,7 +530,9 @@ func (r *KeycloakReconciler) SetupWithManager(mgr ctrl.Manager) error {
 				old := e.ObjectOld.(*v13.StatefulSet)
 				current := e.ObjectNew.(*v13.StatefulSet)
 
-				return !resources.IsStatefulSetReady(old) && resources.IsStatefulSetReady(current)
+				// Replica changes keep status.instances of the scale subresource current
+				return (!resources.IsStatefulSetReady(old) && resources.IsStatefulSetReady(current)) ||
+					old.Status.Replicas != current.Status.Replicas
 			},
 		})).
 		Complete(r)
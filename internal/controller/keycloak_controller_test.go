@@ -18,18 +18,39 @@ package controller
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	route "github.com/openshift/api/route/v1"
+	"github.com/redhat-cop/operator-utils/pkg/util/apis"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	ssov1alpha1 "github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/constants"
+	"github.com/stakater/rhbk-operator/internal/database"
+	"github.com/stakater/rhbk-operator/internal/platform"
+	"github.com/stakater/rhbk-operator/internal/resources/realm"
+	"github.com/stakater/rhbk-operator/internal/resources/rhbk"
 	"github.com/stakater/rhbk-operator/test/utils"
 )
 
@@ -50,7 +71,7 @@ var _ = Describe("Keycloak Controller", func() {
 				},
 				Spec: ssov1alpha1.KeycloakSpec{
 					Instances: &[]int32{1}[0],
-					Database: &ssov1alpha1.PostgresDatabase{
+					Database: &ssov1alpha1.Database{
 						Host:     ssov1alpha1.SecretOption{Value: "rhbk-pguser-rhbk"},
 						Port:     ssov1alpha1.SecretOption{Value: "5432"},
 						User:     ssov1alpha1.SecretOption{Value: "rhbk"},
@@ -107,10 +128,263 @@ var _ = Describe("Keycloak Controller", func() {
 			Expect(statefulSet.Spec.Template.Spec.InitContainers).To(HaveLen(1))
 			Expect(statefulSet.Spec.Template.Spec.InitContainers[0].Args).To(Equal([]string{
 				"-c",
-				"mkdir -p /opt/keycloak/providers; curl -LJ --show-error --cacert conf/truststores/ca-bundle.crt -o /opt/keycloak/providers/keycloak-metrics-spi-6.0.0.jar $(KEYCLOAK_METRICS_SPI_6_0_0_JAR)",
+				"set -eo pipefail; mkdir -p /opt/keycloak/providers; curl -LJ --fail --fail-early --show-error --cacert conf/truststores/ca-bundle.crt -o /opt/keycloak/providers/keycloak-metrics-spi-6.0.0.jar $(KEYCLOAK_METRICS_SPI_6_0_0_JAR)",
 			}))
 		})
 
+		It("should verify provider checksums", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			controllerReconciler := &KeycloakReconciler{
+				Client:          k8sClient,
+				Scheme:          k8sClient.Scheme(),
+				APIReader:       k8sClient,
+				DatabaseChecker: &fakeDatabaseChecker{},
+				Capabilities:    platform.Capabilities{Route: true, ServiceMonitor: true},
+			}
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Providers[0].SHA256 = ssov1alpha1.SecretOption{Value: "0123abcd"}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			fetch := GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.InitContainers[0]
+			Expect(fetch.Env).To(ContainElement(v1.EnvVar{Name: "KEYCLOAK_METRICS_SPI_6_0_0_JAR_SHA256", Value: "0123abcd"}))
+			Expect(fetch.Args[1]).To(HaveSuffix("; echo \"$(KEYCLOAK_METRICS_SPI_6_0_0_JAR_SHA256)  /opt/keycloak/providers/keycloak-metrics-spi-6.0.0.jar\" | sha256sum -c --status" +
+				" || { echo 'checksum mismatch for provider keycloak-metrics-spi-6.0.0.jar' > /dev/termination-log; exit 1; }"))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.ProvidersVerifiedCondition).Status).To(Equal(metav1.ConditionTrue))
+
+			By("Reporting the provider failing verification")
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      keycloak.Name + "-0",
+					Namespace: keycloak.Namespace,
+					Labels:    map[string]string{constants.RHBKInstanceLabel: keycloak.Name},
+				},
+				Spec: v1.PodSpec{Containers: []v1.Container{{Name: "keycloak", Image: "keycloak"}}},
+			}
+			Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+			defer DeleteIfExist(ctx, pod)
+
+			pod.Status.InitContainerStatuses = []v1.ContainerStatus{
+				{
+					Name:  realm.FetchContainerName,
+					State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+					LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{
+						ExitCode: 1,
+						Message:  "checksum mismatch for provider keycloak-metrics-spi-6.0.0.jar\n",
+					}},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, pod)).To(Succeed())
+
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			condition := keycloak.Status.GetCondition(ssov1alpha1.ProvidersVerifiedCondition)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Reason).To(Equal(ssov1alpha1.ProvidersReasonChecksumMismatch))
+			Expect(condition.Message).To(Equal("checksum mismatch for provider keycloak-metrics-spi-6.0.0.jar"))
+
+			By("Rejecting checksums of image providers")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Providers = append(keycloak.Spec.Providers, ssov1alpha1.Provider{
+				Name:   "Custom_SPI.jar",
+				Image:  &ssov1alpha1.ImageSource{Image: "quay.io/acme/spi:1.0", Path: "/providers"},
+				SHA256: ssov1alpha1.SecretOption{Value: "0123abcd"},
+			})
+			Expect(k8sClient.Update(ctx, keycloak)).NotTo(Succeed())
+		})
+
+		It("should download providers from maven repositories", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Providers = []ssov1alpha1.Provider{
+				{
+					Name:  "keycloak-events.jar",
+					Maven: &ssov1alpha1.MavenArtifact{Coordinates: "io.phasetwo.keycloak:keycloak-events:0.30", Repository: "nexus"},
+				},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			By("Rejecting an undeclared repository")
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Conditions.ConditionMsg(apis.ReconcileSuccess)).To(Equal(
+				"Providers setup not ready. maven repository nexus of provider keycloak-events.jar is not declared in spec.mavenRepositories"))
+
+			By("Resolving the coordinates in the declared repository")
+			keycloak.Spec.MavenRepositories = []ssov1alpha1.MavenRepository{
+				{
+					Name:              "nexus",
+					URL:               "https://nexus.example.com/repository/maven-releases/",
+					CredentialsSecret: &v1.LocalObjectReference{Name: "nexus-credentials"},
+					Proxy:             "http://proxy.example.com:3128",
+				},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			fetch := GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.InitContainers[0]
+			Expect(fetch.Env).To(ContainElements(
+				v1.EnvVar{
+					Name:  "KEYCLOAK_EVENTS_JAR",
+					Value: "https://nexus.example.com/repository/maven-releases/io/phasetwo/keycloak/keycloak-events/0.30/keycloak-events-0.30.jar",
+				},
+				HaveField("Name", "MAVEN_NEXUS_USERNAME"),
+				HaveField("Name", "MAVEN_NEXUS_PASSWORD"),
+			))
+			Expect(fetch.Args[1]).To(Equal("set -eo pipefail; mkdir -p /opt/keycloak/providers; curl -LJ --fail --show-error --cacert conf/truststores/ca-bundle.crt" +
+				" --proxy http://proxy.example.com:3128 -u \"${MAVEN_NEXUS_USERNAME}:${MAVEN_NEXUS_PASSWORD}\"" +
+				" -o /opt/keycloak/providers/keycloak-events.jar $(KEYCLOAK_EVENTS_JAR)"))
+		})
+
+		It("should cache providers on a persistent volume", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			cacheKey := client.ObjectKey{Name: rhbk.GetProviderCacheName(keycloak), Namespace: keycloak.Namespace}
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			size := resource.MustParse("2Gi")
+			keycloak.Spec.Providers[0].SHA256 = ssov1alpha1.SecretOption{Value: "0123abcd"}
+			keycloak.Spec.ProviderCache = &ssov1alpha1.ProviderCacheSpec{Size: &size}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			pvc := &v1.PersistentVolumeClaim{}
+			Expect(k8sClient.Get(ctx, cacheKey, pvc)).To(Succeed())
+			Expect(pvc.Spec.AccessModes).To(Equal([]v1.PersistentVolumeAccessMode{v1.ReadWriteMany}))
+			Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("2Gi"))
+
+			sts := GetKeycloakStatefulSet(ctx, keycloak)
+			Expect(sts.Spec.Template.Spec.Volumes).To(ContainElement(v1.Volume{
+				Name: realm.ProviderCacheVolume,
+				VolumeSource: v1.VolumeSource{
+					PersistentVolumeClaim: &v1.PersistentVolumeClaimVolumeSource{ClaimName: cacheKey.Name},
+				},
+			}))
+
+			fetch := sts.Spec.Template.Spec.InitContainers[0]
+			Expect(fetch.VolumeMounts).To(ContainElement(v1.VolumeMount{Name: realm.ProviderCacheVolume, MountPath: realm.ProviderCachePATH}))
+			Expect(sts.Spec.Template.Spec.Containers[0].VolumeMounts).NotTo(ContainElement(HaveField("Name", realm.ProviderCacheVolume)))
+
+			cached := "/mnt/provider-cache/keycloak-metrics-spi-6.0.0.jar/$(KEYCLOAK_METRICS_SPI_6_0_0_JAR_SHA256)"
+			check := "echo \"$(KEYCLOAK_METRICS_SPI_6_0_0_JAR_SHA256)  " + cached + "\" | sha256sum -c --status 2>/dev/null"
+			Expect(fetch.Args[1]).To(HavePrefix("set -eo pipefail; mkdir -p /opt/keycloak/providers; { " + check +
+				" && cp " + cached + " /opt/keycloak/providers/keycloak-metrics-spi-6.0.0.jar" +
+				" || curl -LJ --fail --show-error --cacert conf/truststores/ca-bundle.crt" +
+				" -o /opt/keycloak/providers/keycloak-metrics-spi-6.0.0.jar $(KEYCLOAK_METRICS_SPI_6_0_0_JAR); }"))
+
+			tmp := "/mnt/provider-cache/keycloak-metrics-spi-6.0.0.jar/.$(KEYCLOAK_METRICS_SPI_6_0_0_JAR_SHA256).$HOSTNAME"
+			Expect(fetch.Args[1]).To(HaveSuffix("; " + check + " || { mkdir -p /mnt/provider-cache/keycloak-metrics-spi-6.0.0.jar" +
+				" && cp /opt/keycloak/providers/keycloak-metrics-spi-6.0.0.jar " + tmp + " && mv " + tmp + " " + cached + "; } || true"))
+
+			By("Sharing the cache with import jobs")
+			job := realm.NewJobTemplate(sts, nil)
+			Expect(job.Spec.Volumes).To(ContainElement(HaveField("VolumeSource.PersistentVolumeClaim.ClaimName", cacheKey.Name)))
+
+			By("Using an existing claim")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.ProviderCache = &ssov1alpha1.ProviderCacheSpec{ClaimName: "shared-providers"}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(errors.IsNotFound(k8sClient.Get(ctx, cacheKey, &v1.PersistentVolumeClaim{}))).To(BeTrue())
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Volumes).To(
+				ContainElement(HaveField("VolumeSource.PersistentVolumeClaim.ClaimName", "shared-providers")))
+
+			By("Downloading without a cache")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.ProviderCache = nil
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			sts = GetKeycloakStatefulSet(ctx, keycloak)
+			Expect(sts.Spec.Template.Spec.Volumes).NotTo(ContainElement(HaveField("Name", realm.ProviderCacheVolume)))
+			Expect(sts.Spec.Template.Spec.InitContainers[0].Args[1]).To(HavePrefix("set -eo pipefail; mkdir -p /opt/keycloak/providers; curl -LJ --fail --fail-early --show-error"))
+		})
+
+		It("should deliver themes and image providers", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			controllerReconciler := &KeycloakReconciler{
+				Client:       k8sClient,
+				Scheme:       k8sClient.Scheme(),
+				APIReader:    k8sClient,
+				Capabilities: platform.Capabilities{Route: true, ServiceMonitor: true},
+			}
+			reconcileKeycloak := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			theme := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "login-theme",
+					Namespace: resourceNs,
+					Labels:    map[string]string{constants.RHBKWatchedResourceLabel: "true"},
+				},
+				Data: map[string]string{"theme.properties": "parent=keycloak"},
+			}
+			Expect(k8sClient.Create(ctx, theme)).To(Succeed())
+			defer DeleteIfExist(ctx, theme)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Providers = append(keycloak.Spec.Providers, ssov1alpha1.Provider{
+				Name:  "Custom_SPI.jar",
+				Image: &ssov1alpha1.ImageSource{Image: "quay.io/acme/spi:1.0", Path: "/providers"},
+			})
+			keycloak.Spec.Themes = []ssov1alpha1.Theme{
+				{
+					Name: "acme",
+					ConfigMap: &v1.ConfigMapVolumeSource{
+						LocalObjectReference: v1.LocalObjectReference{Name: theme.Name},
+						Items:                []v1.KeyToPath{{Key: "theme.properties", Path: "login/theme.properties"}},
+					},
+				},
+				{
+					Name:  "portal",
+					Image: &ssov1alpha1.ImageSource{Image: "quay.io/acme/themes:1.0", Path: "/themes/portal/"},
+				},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			reconcileKeycloak()
+			statefulSet := GetKeycloakStatefulSet(ctx, keycloak)
+			initContainers := statefulSet.Spec.Template.Spec.InitContainers
+			Expect(initContainers).To(HaveLen(3))
+			Expect(initContainers[0].Name).To(Equal("fetch"))
+			Expect(initContainers[1].Name).To(Equal("provider-custom-spi-jar"))
+			Expect(initContainers[1].Args).To(Equal([]string{"-c", "mkdir -p /opt/keycloak/providers && cp -R /providers/. /opt/keycloak/providers/"}))
+			Expect(initContainers[2].Name).To(Equal("theme-portal"))
+			Expect(initContainers[2].Args).To(Equal([]string{"-c", "mkdir -p /opt/keycloak/themes/portal && cp -R /themes/portal/. /opt/keycloak/themes/portal/"}))
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElements(
+				v1.VolumeMount{Name: rhbk.ThemesVolume, MountPath: rhbk.ThemesPATH},
+				v1.VolumeMount{Name: "theme-acme", MountPath: "/opt/keycloak/themes/acme", ReadOnly: true},
+			))
+
+			hash := statefulSet.Spec.Template.Annotations[rhbk.ThemesHashAnnotation]
+			Expect(hash).NotTo(BeEmpty())
+
+			By("Rolling the pods when a theme changes")
+			theme.Data["theme.properties"] = "parent=keycloak.v2"
+			Expect(k8sClient.Update(ctx, theme)).To(Succeed())
+			reconcileKeycloak()
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Annotations).To(
+				HaveKeyWithValue(rhbk.ThemesHashAnnotation, Not(Equal(hash))))
+
+			By("Waiting for missing theme configmaps")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Themes[0].ConfigMap.Name = "missing-theme"
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+			reconcileKeycloak()
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Conditions.ConditionMsg(apis.ReconcileSuccess)).To(ContainSubstring("Themes setup not ready"))
+		})
+
 		It("should successfully reconcile resources", func() {
 			key := client.ObjectKeyFromObject(keycloak)
 
@@ -155,6 +429,983 @@ var _ = Describe("Keycloak Controller", func() {
 			Expect(svc.Name).To(Equal(svcName))
 		})
 
+		It("should use image from spec", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+
+			By("Reconciling the keycloak resource")
+			ReconcileKeycloak(ctx, key)
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Image).To(Equal(rhbk.RHBKImage))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Image = "registry.redhat.io/rhbk/keycloak-rhel9:26.0-8"
+			keycloak.Spec.Update = &ssov1alpha1.UpdateSpec{Strategy: ssov1alpha1.UpdateStrategyRollingUpdate}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Image).To(Equal(keycloak.Spec.Image))
+			Expect(rhbk.GetVersion(keycloak.Spec.Image)).To(Equal("26.0-8"))
+		})
+
+		It("should check update compatibility before changing image", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+
+			By("Reconciling the keycloak resource")
+			ReconcileKeycloak(ctx, key)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Image = "registry.redhat.io/rhbk/keycloak-rhel9:26.2-4"
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			By("Waiting for the compatibility job")
+			ReconcileKeycloak(ctx, key)
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Image).To(Equal(rhbk.RHBKImage))
+
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{
+				Name:      rhbk.GetUpdateCompatibilityJobName(keycloak),
+				Namespace: keycloak.Namespace,
+			}, job)).To(Succeed())
+			Expect(job.Annotations).To(HaveKeyWithValue(rhbk.UpdateTargetImageAnnotation, keycloak.Spec.Image))
+			Expect(job.Spec.Template.Spec.Containers[0].Image).To(Equal(keycloak.Spec.Image))
+			Expect(HasOwnerRef(keycloak, job)).To(BeTrue())
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.UpgradeCondition).Reason).To(Equal(ssov1alpha1.UpgradeReasonCompatibilityCheck))
+
+			By("Rolling pods once the check passed")
+			job.Status.Conditions = []batchv1.JobCondition{
+				{Type: batchv1.JobComplete, Status: v1.ConditionTrue},
+			}
+			Expect(k8sClient.Status().Update(ctx, job)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Image).To(Equal(keycloak.Spec.Image))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.UpgradeCondition).Reason).To(Equal(ssov1alpha1.UpgradeReasonRollingUpdate))
+			DeleteIfExist(ctx, job)
+		})
+
+		It("should recreate pods only for incompatible updates", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			jobKey := client.ObjectKey{Name: rhbk.GetUpdateCompatibilityJobName(keycloak), Namespace: keycloak.Namespace}
+			ReconcileKeycloak(ctx, key)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Image = "registry.redhat.io/rhbk/keycloak-rhel9:26.2-4"
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			job := &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, jobKey, job)).To(Succeed())
+			Expect(job.Spec.PodFailurePolicy.Rules[0].OnExitCodes.Values).To(Equal([]int32{rhbk.UpdateIncompatibleExitCode}))
+
+			By("Blocking the upgrade when the check itself fails")
+			job.Status.Conditions = []batchv1.JobCondition{
+				{Type: batchv1.JobFailureTarget, Status: v1.ConditionTrue, Reason: batchv1.JobReasonBackoffLimitExceeded},
+				{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: batchv1.JobReasonBackoffLimitExceeded, Message: "Job has reached the specified backoff limit"},
+			}
+			Expect(k8sClient.Status().Update(ctx, job)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			sts := GetKeycloakStatefulSet(ctx, keycloak)
+			Expect(sts.Spec.Template.Spec.Containers[0].Image).To(Equal(rhbk.RHBKImage))
+			Expect(*sts.Spec.Replicas).To(Equal(int32(1)))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			upgrade := keycloak.Status.GetCondition(ssov1alpha1.UpgradeCondition)
+			Expect(upgrade.Status).To(Equal(metav1.ConditionFalse))
+			Expect(upgrade.Reason).To(Equal(ssov1alpha1.UpgradeReasonCheckFailed))
+			Expect(upgrade.Message).To(ContainSubstring("Job has reached the specified backoff limit"))
+
+			By("Scaling down when the update is incompatible")
+			DeleteIfExist(ctx, job)
+			ReconcileKeycloak(ctx, key)
+
+			job = &batchv1.Job{}
+			Expect(k8sClient.Get(ctx, jobKey, job)).To(Succeed())
+			job.Status.Conditions = []batchv1.JobCondition{
+				{Type: batchv1.JobFailureTarget, Status: v1.ConditionTrue, Reason: batchv1.JobReasonPodFailurePolicy},
+				{Type: batchv1.JobFailed, Status: v1.ConditionTrue, Reason: batchv1.JobReasonPodFailurePolicy},
+			}
+			Expect(k8sClient.Status().Update(ctx, job)).To(Succeed())
+			defer DeleteIfExist(ctx, job)
+
+			ReconcileKeycloak(ctx, key)
+			sts = GetKeycloakStatefulSet(ctx, keycloak)
+			Expect(sts.Spec.Template.Spec.Containers[0].Image).To(Equal(keycloak.Spec.Image))
+			Expect(*sts.Spec.Replicas).To(Equal(int32(0)))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.UpgradeCondition).Reason).To(Equal(ssov1alpha1.UpgradeReasonRecreate))
+
+			By("Scaling up once the pods of the previous version are gone")
+			ReconcileKeycloak(ctx, key)
+			sts = GetKeycloakStatefulSet(ctx, keycloak)
+			Expect(*sts.Spec.Replicas).To(Equal(int32(1)))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.UpgradeCondition).Reason).To(Equal(ssov1alpha1.UpgradeReasonRecreate))
+
+			By("Completing once the new pods are ready")
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.UpgradeCondition).Reason).To(Equal(ssov1alpha1.UpgradeReasonRecreate))
+
+			sts = GetKeycloakStatefulSet(ctx, keycloak)
+			sts.Status = appsv1.StatefulSetStatus{
+				ObservedGeneration: sts.Generation,
+				Replicas:           1,
+				ReadyReplicas:      1,
+				CurrentRevision:    "rev",
+				UpdateRevision:     "rev",
+			}
+			Expect(k8sClient.Status().Update(ctx, sts)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.UpgradeCondition).Reason).To(Equal(ssov1alpha1.UpgradeReasonCompleted))
+		})
+
+		It("should configure database vendor", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Database.Vendor = ssov1alpha1.DatabaseVendorMSSQL
+			keycloak.Spec.Database.Schema = "sso"
+			keycloak.Spec.Database.URL = &ssov1alpha1.SecretOption{Value: "jdbc:sqlserver://mssql:1433;databaseName=keycloak"}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+
+			env := GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Env
+			Expect(env).To(ContainElements(
+				v1.EnvVar{Name: "KC_DB", Value: "mssql"},
+				v1.EnvVar{Name: "KC_DB_SCHEMA", Value: "sso"},
+				v1.EnvVar{Name: "KC_DB_URL", Value: "jdbc:sqlserver://mssql:1433;databaseName=keycloak"},
+			))
+			Expect(env).NotTo(ContainElement(HaveField("Name", "KC_DB_URL_HOST")))
+		})
+
+		It("should size database pool", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+
+			By("Using the default pool")
+			ReconcileKeycloak(ctx, key)
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Env).To(ContainElement(v1.EnvVar{Name: "KC_DB_POOL_MAX_SIZE", Value: "30"}))
+
+			By("Deriving the pool from sizing")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Instances = &[]int32{2}[0]
+			keycloak.Spec.Sizing = &ssov1alpha1.RealmSizing{LoginsPerSecond: 30}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Env).To(ContainElements(
+				v1.EnvVar{Name: "KC_DB_POOL_INITIAL_SIZE", Value: "10"},
+				v1.EnvVar{Name: "KC_DB_POOL_MIN_SIZE", Value: "10"},
+				v1.EnvVar{Name: "KC_DB_POOL_MAX_SIZE", Value: "10"},
+			))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Database.MaxConnections).To(Equal(int32(20)))
+
+			By("Preferring explicit pool settings")
+			keycloak.Spec.Database.Pool = &ssov1alpha1.DatabasePool{MinSize: &[]int32{5}[0], MaxSize: &[]int32{15}[0]}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Env).To(ContainElements(
+				v1.EnvVar{Name: "KC_DB_POOL_INITIAL_SIZE", Value: "5"},
+				v1.EnvVar{Name: "KC_DB_POOL_MIN_SIZE", Value: "5"},
+				v1.EnvVar{Name: "KC_DB_POOL_MAX_SIZE", Value: "15"},
+			))
+
+			By("Raising the derived max size to the min size")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Database.Pool = &ssov1alpha1.DatabasePool{MinSize: &[]int32{40}[0]}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Env).To(ContainElements(
+				v1.EnvVar{Name: "KC_DB_POOL_INITIAL_SIZE", Value: "40"},
+				v1.EnvVar{Name: "KC_DB_POOL_MIN_SIZE", Value: "40"},
+				v1.EnvVar{Name: "KC_DB_POOL_MAX_SIZE", Value: "40"},
+			))
+
+			By("Raising the derived max size to the initial size")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Database.Pool = &ssov1alpha1.DatabasePool{InitialSize: &[]int32{50}[0], MinSize: &[]int32{5}[0]}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Env).To(ContainElements(
+				v1.EnvVar{Name: "KC_DB_POOL_INITIAL_SIZE", Value: "50"},
+				v1.EnvVar{Name: "KC_DB_POOL_MIN_SIZE", Value: "5"},
+				v1.EnvVar{Name: "KC_DB_POOL_MAX_SIZE", Value: "50"},
+			))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Database.MaxConnections).To(Equal(int32(100)))
+
+			By("Rejecting an initial size above the max size")
+			keycloak.Spec.Database.Pool = &ssov1alpha1.DatabasePool{InitialSize: &[]int32{50}[0], MaxSize: &[]int32{10}[0]}
+			Expect(k8sClient.Update(ctx, keycloak)).NotTo(Succeed())
+		})
+
+		It("should mount database CA", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			controllerReconciler := &KeycloakReconciler{
+				Client:          k8sClient,
+				Scheme:          k8sClient.Scheme(),
+				APIReader:       k8sClient,
+				DatabaseChecker: &fakeDatabaseChecker{},
+				Capabilities:    platform.Capabilities{Route: true, ServiceMonitor: true},
+			}
+			reconcileKeycloak := func() {
+				_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			reconcileKeycloak()
+
+			ca := &v1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "database-ca",
+					Namespace: resourceNs,
+				},
+				Data: map[string]string{
+					"ca.crt": "not a certificate",
+				},
+			}
+			Expect(k8sClient.Create(ctx, ca)).To(Succeed())
+			defer DeleteIfExist(ctx, ca)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Database.Parameters = map[string]string{"connectTimeout": "10"}
+			keycloak.Spec.Database.TLS = &ssov1alpha1.DatabaseTLS{
+				Mode: ssov1alpha1.DatabaseTLSModeVerifyFull,
+				CA: &ssov1alpha1.CertificateSource{
+					ConfigMap: &v1.ConfigMapKeySelector{
+						LocalObjectReference: v1.LocalObjectReference{Name: ca.Name},
+						Key:                  "ca.crt",
+					},
+				},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			By("Keeping pods while the CA is invalid")
+			reconcileKeycloak()
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Volumes).NotTo(ContainElement(HaveField("Name", rhbk.DatabaseCAVolume)))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Conditions.ConditionMsg(apis.ReconcileSuccess)).To(ContainSubstring("Database TLS setup not ready"))
+
+			By("Mounting a valid CA")
+			ca.Data["ca.crt"] = NewTestCertificate()
+			Expect(k8sClient.Update(ctx, ca)).To(Succeed())
+
+			reconcileKeycloak()
+			statefulSet := GetKeycloakStatefulSet(ctx, keycloak)
+			Expect(statefulSet.Spec.Template.Spec.Volumes).To(ContainElement(HaveField("Name", rhbk.DatabaseCAVolume)))
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].VolumeMounts).To(ContainElement(HaveField("MountPath", rhbk.DatabaseCAPath)))
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].Env).To(ContainElement(v1.EnvVar{
+				Name:  "KC_DB_URL_PROPERTIES",
+				Value: "?sslmode=verify-full&sslrootcert=/mnt/database-ca/ca.crt&connectTimeout=10",
+			}))
+
+			By("Reading a CA secret without the watched label")
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "database-ca", Namespace: resourceNs},
+				StringData: map[string]string{"ca.crt": NewTestCertificate()},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+			defer DeleteIfExist(ctx, secret)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Database.TLS.CA = &ssov1alpha1.CertificateSource{
+				Secret: &v1.SecretKeySelector{
+					LocalObjectReference: v1.LocalObjectReference{Name: secret.Name},
+					Key:                  "ca.crt",
+				},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			reconcileKeycloak()
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Conditions.ConditionMsg(apis.ReconcileSuccess)).NotTo(ContainSubstring("Database TLS setup not ready"))
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Volumes).To(ContainElement(HaveField("Secret.SecretName", secret.Name)))
+		})
+
+		It("should check database before rollout", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			checker := &fakeDatabaseChecker{err: fmt.Errorf("password authentication failed for user \"rhbk\"")}
+			controllerReconciler := &KeycloakReconciler{
+				Client:          k8sClient,
+				Scheme:          k8sClient.Scheme(),
+				APIReader:       k8sClient,
+				DatabaseChecker: checker,
+				Capabilities:    platform.Capabilities{Route: true, ServiceMonitor: true},
+			}
+
+			By("Failing the check")
+			result, err := controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(DatabaseRequeueInterval))
+			Expect(k8sClient.Get(ctx, key, &appsv1.StatefulSet{})).NotTo(Succeed())
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			condition := keycloak.Status.GetCondition(ssov1alpha1.DatabaseReadyCondition)
+			Expect(condition.Status).To(Equal(metav1.ConditionFalse))
+			Expect(condition.Message).To(ContainSubstring("password authentication failed"))
+
+			By("Passing the check")
+			checker.err = nil
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Replicas).To(Equal(keycloak.Spec.Instances))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.DatabaseReadyCondition).Status).To(Equal(metav1.ConditionTrue))
+			Expect(checker.checked).To(Equal(2))
+
+			By("Skipping the check when settings are unchanged")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checker.checked).To(Equal(2))
+
+			By("Checking again when settings change")
+			keycloak.Spec.Database.Port = ssov1alpha1.SecretOption{Value: "5433"}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checker.checked).To(Equal(3))
+			Expect(checker.cfg.Address()).To(Equal("rhbk-pguser-rhbk:5433"))
+
+			By("Checking again when the password secret is rotated")
+			secret := &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "rhbk-pguser-rhbk", Namespace: resourceNs},
+				Data:       map[string][]byte{"password": []byte("rhbk")},
+			}
+			Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+			defer DeleteIfExist(ctx, secret)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Database.Password = ssov1alpha1.SecretOption{Secret: &v1.SecretKeySelector{
+				LocalObjectReference: v1.LocalObjectReference{Name: secret.Name},
+				Key:                  "password",
+			}}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checker.checked).To(Equal(4))
+
+			secret.Data["password"] = []byte("wrong")
+			Expect(k8sClient.Update(ctx, secret)).To(Succeed())
+			checker.err = fmt.Errorf("password authentication failed for user \"rhbk\"")
+			_, err = controllerReconciler.Reconcile(ctx, reconcile.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(checker.checked).To(Equal(5))
+			Expect(checker.cfg.Password).To(Equal("wrong"))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.DatabaseReadyCondition).Status).To(Equal(metav1.ConditionFalse))
+		})
+
+		It("should configure features", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			ReconcileKeycloak(ctx, key)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Features).To(ContainElement("organization"))
+			Expect(keycloak.Status.Features).NotTo(ContainElement("docker"))
+
+			By("Rejecting unknown features")
+			keycloak.Spec.Features = &ssov1alpha1.Features{Enabled: []string{"docker", "teleport"}}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Env).NotTo(ContainElement(HaveField("Name", "KC_FEATURES")))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Conditions.ConditionMsg(apis.ReconcileSuccess)).To(ContainSubstring("unknown features for RHBK 26.0-6: teleport"))
+
+			By("Rendering valid features")
+			keycloak.Spec.Features = &ssov1alpha1.Features{
+				Enabled:  []string{"docker", "token-exchange:v1"},
+				Disabled: []string{"organization", "impersonation"},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Env).To(ContainElements(
+				v1.EnvVar{Name: "KC_FEATURES", Value: "docker,token-exchange:v1"},
+				v1.EnvVar{Name: "KC_FEATURES_DISABLED", Value: "organization,impersonation"},
+			))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Features).To(ContainElements("docker", "token-exchange:v1", "web-authn"))
+			Expect(keycloak.Status.Features).NotTo(ContainElements("organization", "impersonation"))
+		})
+
+		It("should add volumes and options", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+
+			options := v1.LocalObjectReference{Name: "keycloak-options"}
+			keycloak.Spec.AdditionalOptions = []ssov1alpha1.SecretOptionVar{
+				{
+					Name:      "KC_LOG_LEVEL",
+					ConfigMap: &v1.ConfigMapKeySelector{LocalObjectReference: options, Key: "log-level"},
+				},
+			}
+			keycloak.Spec.EnvFrom = []v1.EnvFromSource{
+				{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: options}},
+			}
+			keycloak.Spec.Volumes = []v1.Volume{
+				{Name: "themes", VolumeSource: v1.VolumeSource{ConfigMap: &v1.ConfigMapVolumeSource{LocalObjectReference: options}}},
+			}
+			keycloak.Spec.VolumeMounts = []v1.VolumeMount{
+				{Name: "themes", MountPath: "/opt/keycloak/themes/custom"},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			statefulSet := GetKeycloakStatefulSet(ctx, keycloak)
+			container := statefulSet.Spec.Template.Spec.Containers[0]
+			Expect(container.Env).To(ContainElement(v1.EnvVar{
+				Name: "KC_LOG_LEVEL",
+				ValueFrom: &v1.EnvVarSource{
+					ConfigMapKeyRef: &v1.ConfigMapKeySelector{LocalObjectReference: options, Key: "log-level"},
+				},
+			}))
+			Expect(container.EnvFrom).To(Equal(keycloak.Spec.EnvFrom))
+			Expect(container.VolumeMounts).To(ContainElement(HaveField("MountPath", "/opt/keycloak/themes/custom")))
+			Expect(statefulSet.Spec.Template.Spec.Volumes).To(ContainElement(HaveField("Name", "themes")))
+
+			By("Carrying them over to import jobs")
+			template := realm.NewJobTemplate(statefulSet, nil)
+			Expect(template.Spec.Containers[0].EnvFrom).To(Equal(keycloak.Spec.EnvFrom))
+			Expect(template.Spec.Containers[0].VolumeMounts).To(ContainElement(HaveField("Name", "themes")))
+			Expect(template.Spec.Volumes).To(ContainElement(HaveField("Name", "themes")))
+		})
+
+		It("should merge unsupported pod template", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+
+			keycloak.Spec.Unsupported = &ssov1alpha1.UnsupportedSpec{
+				PodTemplate: &v1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels:      map[string]string{"team": "iam"},
+						Annotations: map[string]string{"sidecar.istio.io/inject": "false"},
+					},
+					Spec: v1.PodSpec{
+						HostAliases: []v1.HostAlias{{IP: "10.0.0.1", Hostnames: []string{"ldap"}}},
+					},
+				},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			statefulSet := GetKeycloakStatefulSet(ctx, keycloak)
+			Expect(statefulSet.Spec.Template.Labels).To(HaveKeyWithValue("team", "iam"))
+			Expect(statefulSet.Spec.Template.Annotations).To(HaveKeyWithValue("sidecar.istio.io/inject", "false"))
+			Expect(statefulSet.Spec.Template.Spec.HostAliases).To(HaveLen(1))
+			Expect(statefulSet.Spec.Template.Spec.Containers).To(HaveLen(1))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.UnsupportedCondition).Status).To(Equal(metav1.ConditionTrue))
+
+			By("Applying the overlay to import jobs")
+			kci := &ssov1alpha1.KeycloakImport{ObjectMeta: metav1.ObjectMeta{Name: "realm", Namespace: resourceNs}}
+			job, err := realm.Build(kci, keycloak, statefulSet, "1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(job.Spec.Template.Labels).To(HaveKeyWithValue("team", "iam"))
+			Expect(job.Spec.Template.Labels).NotTo(HaveKey(constants.RHBKInstanceLabel))
+			Expect(job.Spec.Template.Spec.HostAliases).To(HaveLen(1))
+
+			By("Removing the warning without overlay")
+			keycloak.Spec.Unsupported = nil
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.UnsupportedCondition)).To(BeNil())
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.HostAliases).To(BeEmpty())
+		})
+
+		It("should expose through ingress", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, &route.Route{})).To(Succeed())
+
+			By("Requiring a hostname")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Ingress = &ssov1alpha1.IngressSpec{
+				ClassName:   &[]string{"nginx"}[0],
+				Annotations: map[string]string{"cert-manager.io/cluster-issuer": "letsencrypt"},
+				TLSSecret:   "keycloak-example-com",
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Conditions.ConditionMsg(apis.ReconcileSuccess)).To(ContainSubstring("networkOptions.hostname is required"))
+
+			By("Replacing the route")
+			keycloak.Spec.NetworkConfig = &ssov1alpha1.NetworkConfig{Hostname: "keycloak.example.com"}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, &route.Route{})).NotTo(Succeed())
+
+			ingress := &networkingv1.Ingress{}
+			Expect(k8sClient.Get(ctx, key, ingress)).To(Succeed())
+			Expect(HasOwnerRef(keycloak, ingress)).To(BeTrue())
+			Expect(*ingress.Spec.IngressClassName).To(Equal("nginx"))
+			Expect(ingress.Annotations).To(HaveKeyWithValue(rhbk.IngressBackendProtocolAnnotation, "HTTPS"))
+			Expect(ingress.Annotations).To(HaveKeyWithValue("cert-manager.io/cluster-issuer", "letsencrypt"))
+			Expect(ingress.Spec.Rules[0].Host).To(Equal("keycloak.example.com"))
+			Expect(ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service.Name).To(Equal(rhbk.GetSvcName(keycloak)))
+			Expect(ingress.Spec.TLS).To(ConsistOf(networkingv1.IngressTLS{
+				Hosts:      []string{"keycloak.example.com"},
+				SecretName: "keycloak-example-com",
+			}))
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Env).To(ContainElement(v1.EnvVar{Name: "KC_HOSTNAME", Value: "keycloak.example.com"}))
+		})
+
+		It("should use ingress without route API", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.NetworkConfig = &ssov1alpha1.NetworkConfig{Hostname: "keycloak.example.com"}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloakOn(ctx, key, platform.Capabilities{})
+			Expect(k8sClient.Get(ctx, key, &networkingv1.Ingress{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, key, &route.Route{})).NotTo(Succeed())
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Name).To(Equal(resourceName))
+		})
+
+		It("should keep resources of users named after the instance", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			pathType := networkingv1.PathTypePrefix
+			ingress := &networkingv1.Ingress{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec: networkingv1.IngressSpec{
+					Rules: []networkingv1.IngressRule{{
+						Host: "legacy.example.com",
+						IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+							Paths: []networkingv1.HTTPIngressPath{{
+								Path:     "/",
+								PathType: &pathType,
+								Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+									Name: "legacy", Port: networkingv1.ServiceBackendPort{Number: 8443},
+								}},
+							}},
+						}},
+					}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, ingress)).To(Succeed())
+			defer DeleteIfExist(ctx, ingress)
+
+			pdb := &policyv1.PodDisruptionBudget{
+				ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace},
+				Spec: policyv1.PodDisruptionBudgetSpec{
+					MaxUnavailable: &[]intstr.IntOrString{intstr.FromInt32(1)}[0],
+					Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "legacy"}},
+				},
+			}
+			Expect(k8sClient.Create(ctx, pdb)).To(Succeed())
+			defer DeleteIfExist(ctx, pdb)
+
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, &route.Route{})).To(Succeed())
+			Expect(k8sClient.Get(ctx, key, ingress)).To(Succeed())
+			Expect(k8sClient.Get(ctx, key, pdb)).To(Succeed())
+			Expect(HasOwnerRef(keycloak, ingress)).To(BeFalse())
+		})
+
+		It("should expose through gateway", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			capabilities := platform.Capabilities{Route: true, HTTPRoute: true, TLSRoute: true, BackendTLSPolicy: true}
+			ReconcileKeycloakOn(ctx, key, capabilities)
+
+			gateway := rhbk.NewUnstructured(rhbk.GatewayGVK, "public", resourceNs)
+			gateway.Object["spec"] = map[string]interface{}{
+				"gatewayClassName": "example",
+				"listeners": []interface{}{
+					map[string]interface{}{"name": "http", "hostname": "*.example.com", "port": int64(80), "protocol": "HTTP"},
+					map[string]interface{}{"name": "https", "hostname": "*.apps.example.com", "port": int64(443), "protocol": "HTTPS"},
+				},
+			}
+			Expect(k8sClient.Create(ctx, gateway)).To(Succeed())
+			defer DeleteIfExist(ctx, gateway)
+
+			By("Requiring the CA of Keycloak to reencrypt")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.NetworkConfig = &ssov1alpha1.NetworkConfig{
+				Exposure: ssov1alpha1.ExposureGateway,
+				Gateway: &ssov1alpha1.GatewaySpec{
+					ParentRef: ssov1alpha1.GatewayParentRef{Name: "public", SectionName: "https"},
+				},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).NotTo(Succeed())
+
+			keycloak.Spec.NetworkConfig.Gateway.BackendCACertificate = &v1.LocalObjectReference{Name: "keycloak-ca"}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			By("Reencrypting through an HTTPRoute")
+			ReconcileKeycloakOn(ctx, key, capabilities)
+			Expect(k8sClient.Get(ctx, key, &route.Route{})).NotTo(Succeed())
+
+			hostname := "test-resource-rhbk-instance.apps.example.com"
+			httpRoute := rhbk.NewUnstructured(rhbk.HTTPRouteGVK, keycloak.Name, keycloak.Namespace)
+			Expect(k8sClient.Get(ctx, key, httpRoute)).To(Succeed())
+			Expect(HasOwnerRef(keycloak, httpRoute)).To(BeTrue())
+			Expect(httpRoute.Object["spec"]).To(HaveKeyWithValue("hostnames", ConsistOf(hostname)))
+			Expect(httpRoute.Object["spec"]).To(HaveKeyWithValue("parentRefs", ConsistOf(HaveKeyWithValue("sectionName", "https"))))
+
+			policy := rhbk.NewUnstructured(rhbk.BackendTLSPolicyGVK, keycloak.Name, keycloak.Namespace)
+			Expect(k8sClient.Get(ctx, key, policy)).To(Succeed())
+			Expect(policy.Object["spec"]).To(HaveKeyWithValue("validation", HaveKeyWithValue("hostname", "test-resource-svc.rhbk-instance.svc")))
+			Expect(policy.Object["spec"]).To(HaveKeyWithValue("validation", HaveKeyWithValue("caCertificateRefs",
+				ConsistOf(HaveKeyWithValue("name", "keycloak-ca")))))
+			Expect(policy.Object["spec"]).NotTo(HaveKeyWithValue("validation", HaveKey("wellKnownCACertificates")))
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Env).To(ContainElement(v1.EnvVar{Name: "KC_HOSTNAME", Value: hostname}))
+
+			By("Passing TLS through a TLSRoute")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.NetworkConfig.Gateway.TLSTermination = ssov1alpha1.GatewayTLSPassthrough
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloakOn(ctx, key, capabilities)
+			Expect(k8sClient.Get(ctx, key, rhbk.NewUnstructured(rhbk.TLSRouteGVK, "", ""))).To(Succeed())
+			Expect(k8sClient.Get(ctx, key, rhbk.NewUnstructured(rhbk.HTTPRouteGVK, "", ""))).NotTo(Succeed())
+			Expect(k8sClient.Get(ctx, key, rhbk.NewUnstructured(rhbk.BackendTLSPolicyGVK, "", ""))).NotTo(Succeed())
+		})
+
+		It("should use certificate from cert-manager", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			capabilities := platform.Capabilities{Route: true, CertManager: true}
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.NetworkConfig = &ssov1alpha1.NetworkConfig{Hostname: "keycloak.example.com"}
+			keycloak.Spec.TLS = &ssov1alpha1.TLSSpec{
+				IssuerRef:    &ssov1alpha1.IssuerReference{Name: "internal-ca", Kind: "ClusterIssuer"},
+				ReloadPeriod: &metav1.Duration{Duration: 10 * time.Minute},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloakOn(ctx, key, capabilities)
+
+			certificate := rhbk.NewUnstructured(rhbk.CertificateGVK, "", "")
+			Expect(k8sClient.Get(ctx, key, certificate)).To(Succeed())
+			Expect(HasOwnerRef(keycloak, certificate)).To(BeTrue())
+			Expect(certificate.Object["spec"]).To(HaveKeyWithValue("secretName", rhbk.GetTLSSecretName(keycloak)))
+			Expect(certificate.Object["spec"]).To(HaveKeyWithValue("dnsNames", ContainElements(
+				"test-resource-svc.rhbk-instance.svc",
+				"keycloak.example.com",
+			)))
+			Expect(certificate.Object["spec"]).To(HaveKeyWithValue("issuerRef", HaveKeyWithValue("kind", "ClusterIssuer")))
+
+			service := &v1.Service{}
+			Expect(k8sClient.Get(ctx, client.ObjectKey{Name: rhbk.GetSvcName(keycloak), Namespace: resourceNs}, service)).To(Succeed())
+			Expect(service.Annotations).NotTo(HaveKey(rhbk.ServingCertAnnotation))
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Env).To(
+				ContainElement(v1.EnvVar{Name: "KC_HTTPS_CERTIFICATES_RELOAD_PERIOD", Value: "600s"}))
+
+			By("Using an existing secret")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.TLS = &ssov1alpha1.TLSSpec{SecretName: "keycloak-certificate"}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloakOn(ctx, key, capabilities)
+			Expect(k8sClient.Get(ctx, key, rhbk.NewUnstructured(rhbk.CertificateGVK, "", ""))).NotTo(Succeed())
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Volumes).To(ContainElement(
+				HaveField("VolumeSource.Secret.SecretName", "keycloak-certificate")))
+		})
+
+		It("should schedule pods", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			ReconcileKeycloak(ctx, key)
+
+			By("Spreading pods of the instance by default")
+			podSpec := GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec
+			Expect(podSpec.Affinity.PodAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution).To(ConsistOf(
+				HaveField("PodAffinityTerm.TopologyKey", v1.LabelHostname)))
+			Expect(podSpec.TopologySpreadConstraints).To(ConsistOf(And(
+				HaveField("TopologyKey", v1.LabelTopologyZone),
+				HaveField("WhenUnsatisfiable", v1.ScheduleAnyway),
+				HaveField("LabelSelector.MatchLabels", HaveKeyWithValue(constants.RHBKInstanceLabel, keycloak.Name)),
+			)))
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Labels).To(HaveKeyWithValue(constants.RHBKInstanceLabel, keycloak.Name))
+
+			By("Using configured scheduling")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Scheduling = &ssov1alpha1.SchedulingSpec{
+				NodeSelector: map[string]string{"node-role.kubernetes.io/infra": ""},
+				Tolerations: []v1.Toleration{
+					{Key: "node-role.kubernetes.io/infra", Operator: v1.TolerationOpExists, Effect: v1.TaintEffectNoSchedule},
+				},
+				Affinity: &v1.Affinity{},
+				TopologySpreadConstraints: []v1.TopologySpreadConstraint{
+					{MaxSkew: 1, TopologyKey: v1.LabelHostname, WhenUnsatisfiable: v1.DoNotSchedule},
+				},
+				PriorityClassName: "system-cluster-critical",
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			podSpec = GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec
+			Expect(podSpec.NodeSelector).To(HaveKey("node-role.kubernetes.io/infra"))
+			Expect(podSpec.Tolerations).To(HaveLen(1))
+			Expect(podSpec.Affinity).To(BeNil())
+			Expect(podSpec.TopologySpreadConstraints).To(ConsistOf(HaveField("WhenUnsatisfiable", v1.DoNotSchedule)))
+			Expect(podSpec.PriorityClassName).To(Equal("system-cluster-critical"))
+		})
+
+		It("should configure probes and graceful shutdown", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			ReconcileKeycloak(ctx, key)
+
+			statefulSet := GetKeycloakStatefulSet(ctx, keycloak)
+			container := statefulSet.Spec.Template.Spec.Containers[0]
+			Expect(*statefulSet.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(int64(40)))
+			Expect(container.Lifecycle.PreStop.Exec.Command).To(Equal([]string{"/bin/bash", "-c", "sleep 10"}))
+			Expect(container.StartupProbe.FailureThreshold).To(Equal(int32(600)))
+			Expect(container.ReadinessProbe.PeriodSeconds).To(Equal(int32(10)))
+
+			By("Overriding timings")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Lifecycle = &ssov1alpha1.LifecycleSpec{
+				StartupProbe:   &ssov1alpha1.ProbeTimings{PeriodSeconds: &[]int32{5}[0], FailureThreshold: &[]int32{60}[0]},
+				ReadinessProbe: &ssov1alpha1.ProbeTimings{TimeoutSeconds: &[]int32{3}[0]},
+				ShutdownDelay:  &metav1.Duration{Duration: 0},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			statefulSet = GetKeycloakStatefulSet(ctx, keycloak)
+			container = statefulSet.Spec.Template.Spec.Containers[0]
+			Expect(*statefulSet.Spec.Template.Spec.TerminationGracePeriodSeconds).To(Equal(int64(30)))
+			Expect(container.Lifecycle).To(BeNil())
+			Expect(container.StartupProbe.PeriodSeconds).To(Equal(int32(5)))
+			Expect(container.StartupProbe.FailureThreshold).To(Equal(int32(60)))
+			Expect(container.ReadinessProbe.TimeoutSeconds).To(Equal(int32(3)))
+			Expect(container.ReadinessProbe.PeriodSeconds).To(Equal(int32(10)))
+
+			By("Rejecting a grace period shorter than the delay")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Lifecycle = &ssov1alpha1.LifecycleSpec{
+				ShutdownDelay:                 &metav1.Duration{Duration: time.Minute},
+				TerminationGracePeriodSeconds: &[]int64{30}[0],
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).NotTo(Succeed())
+		})
+
+		It("should manage disruption budget", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, &policyv1.PodDisruptionBudget{})).NotTo(Succeed())
+
+			By("Protecting multiple instances")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Instances = &[]int32{3}[0]
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			pdb := &policyv1.PodDisruptionBudget{}
+			Expect(k8sClient.Get(ctx, key, pdb)).To(Succeed())
+			Expect(HasOwnerRef(keycloak, pdb)).To(BeTrue())
+			Expect(*pdb.Spec.MaxUnavailable).To(Equal(intstr.FromInt32(1)))
+			Expect(pdb.Spec.Selector.MatchLabels).To(HaveKeyWithValue(constants.RHBKInstanceLabel, keycloak.Name))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.DisruptionBudget).To(Equal(&ssov1alpha1.DisruptionBudgetStatus{
+				Name:           pdb.Name,
+				MaxUnavailable: intstr.FromInt32(1),
+			}))
+
+			By("Using configured max unavailable")
+			keycloak.Spec.DisruptionBudget = &ssov1alpha1.DisruptionBudgetSpec{
+				MaxUnavailable: &[]intstr.IntOrString{intstr.FromString("50%")}[0],
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, pdb)).To(Succeed())
+			Expect(*pdb.Spec.MaxUnavailable).To(Equal(intstr.FromString("50%")))
+
+			By("Removing it for a single instance")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Instances = &[]int32{1}[0]
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, &policyv1.PodDisruptionBudget{})).NotTo(Succeed())
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.DisruptionBudget).To(BeNil())
+		})
+
+		It("should autoscale", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Sizing = &ssov1alpha1.RealmSizing{LoginsPerSecond: 60, CachedSessions: 10000}
+			keycloak.Spec.Autoscaling = &ssov1alpha1.AutoscalingSpec{MinReplicas: 2, MaxReplicas: 5}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			hpa := &autoscalingv2.HorizontalPodAutoscaler{}
+			Expect(k8sClient.Get(ctx, key, hpa)).To(Succeed())
+			Expect(HasOwnerRef(keycloak, hpa)).To(BeTrue())
+			Expect(hpa.Spec.ScaleTargetRef.Kind).To(Equal("StatefulSet"))
+			Expect(*hpa.Spec.MinReplicas).To(Equal(int32(2)))
+			Expect(hpa.Spec.MaxReplicas).To(Equal(int32(5)))
+			Expect(*hpa.Spec.Metrics[0].Resource.Target.AverageUtilization).To(Equal(int32(rhbk.DefaultTargetCPUUtilizationPercentage)))
+
+			statefulSet := GetKeycloakStatefulSet(ctx, keycloak)
+			Expect(*statefulSet.Spec.Replicas).To(Equal(int32(2)))
+			Expect(statefulSet.Spec.Template.Spec.Containers[0].Resources).To(Equal(keycloak.Spec.Sizing.CalculateResourceLimits(&[]int32{2}[0])))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Selector).To(Equal(constants.RHBKInstanceLabel + "=" + keycloak.Name))
+			Expect(keycloak.Status.DisruptionBudget).NotTo(BeNil())
+
+			By("Keeping replicas set by the autoscaler")
+			statefulSet.Spec.Replicas = &[]int32{4}[0]
+			Expect(k8sClient.Update(ctx, statefulSet)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(*GetKeycloakStatefulSet(ctx, keycloak).Spec.Replicas).To(Equal(int32(4)))
+
+			By("Returning to instances")
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Autoscaling = nil
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, &autoscalingv2.HorizontalPodAutoscaler{})).NotTo(Succeed())
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Replicas).To(Equal(keycloak.Spec.Instances))
+		})
+
+		It("should override resources", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Sizing = &ssov1alpha1.RealmSizing{LoginsPerSecond: 15, CachedSessions: 10000}
+			keycloak.Spec.Resources = &v1.ResourceRequirements{
+				Requests: v1.ResourceList{v1.ResourceEphemeralStorage: resource.MustParse("1Gi")},
+				Limits:   v1.ResourceList{v1.ResourceMemory: resource.MustParse("3Gi")},
+			}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			containerResources := GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Resources
+			Expect(containerResources.Requests).To(HaveKeyWithValue(v1.ResourceCPU, resource.MustParse("1")))
+			Expect(containerResources.Requests).To(HaveKeyWithValue(v1.ResourceEphemeralStorage, resource.MustParse("1Gi")))
+			Expect(containerResources.Limits).To(HaveKeyWithValue(v1.ResourceMemory, resource.MustParse("3Gi")))
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Resources).To(Equal(&containerResources))
+		})
+
+		It("should size session caches", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Instances = &[]int32{4}[0]
+			keycloak.Spec.Sizing = &ssov1alpha1.RealmSizing{CachedSessions: 100000, CachedOfflineSessions: 20000}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			container := GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0]
+			Expect(container.Env).To(ContainElements(
+				v1.EnvVar{Name: "JAVA_OPTS_KC_HEAP", Value: "-XX:MaxRAMPercentage=70 -XX:MinRAMPercentage=70 -XX:InitialRAMPercentage=50"},
+				v1.EnvVar{Name: "KC_CACHE_EMBEDDED_SESSIONS_MAX_COUNT", Value: "50000"},
+				v1.EnvVar{Name: "KC_CACHE_EMBEDDED_OFFLINE_SESSIONS_MAX_COUNT", Value: "10000"},
+			))
+			Expect(container.Resources).To(Equal(keycloak.Spec.Sizing.CalculateResourceLimits(keycloak.Spec.Instances)))
+		})
+
+		It("should sum sizing of imports", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Sizing = &ssov1alpha1.RealmSizing{LoginsPerSecond: 15, CachedSessions: 10000}
+			keycloak.Spec.DefaultRealmSizing = &ssov1alpha1.RealmSizing{LoginsPerSecond: 5, CachedSessions: 1000}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			target := ssov1alpha1.KeycloakInstance{Name: keycloak.Name, Namespace: keycloak.Namespace}
+			sized := &ssov1alpha1.KeycloakImport{
+				ObjectMeta: metav1.ObjectMeta{Name: "tenant-a", Namespace: resourceNs},
+				Spec: ssov1alpha1.KeycloakImportSpec{
+					KeycloakInstance: target,
+					Sizing:           &ssov1alpha1.RealmSizing{LoginsPerSecond: 10, CachedSessions: 5000},
+				},
+			}
+			unsized := &ssov1alpha1.KeycloakImport{
+				ObjectMeta: metav1.ObjectMeta{Name: "tenant-b", Namespace: resourceNs},
+				Spec:       ssov1alpha1.KeycloakImportSpec{KeycloakInstance: target},
+			}
+			other := &ssov1alpha1.KeycloakImport{
+				ObjectMeta: metav1.ObjectMeta{Name: "tenant-c", Namespace: resourceNs},
+				Spec: ssov1alpha1.KeycloakImportSpec{
+					KeycloakInstance: ssov1alpha1.KeycloakInstance{Name: "other", Namespace: resourceNs},
+					Sizing:           &ssov1alpha1.RealmSizing{LoginsPerSecond: 100},
+				},
+			}
+			for _, kci := range []*ssov1alpha1.KeycloakImport{sized, unsized, other} {
+				Expect(k8sClient.Create(ctx, kci)).To(Succeed())
+				defer DeleteIfExist(ctx, kci)
+			}
+
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Sizing).To(Equal(&ssov1alpha1.RealmSizing{LoginsPerSecond: 30, CachedSessions: 16000}))
+			Expect(GetKeycloakStatefulSet(ctx, keycloak).Spec.Template.Spec.Containers[0].Resources.Requests).To(
+				HaveKeyWithValue(v1.ResourceCPU, resource.MustParse("2")))
+
+			By("Resizing when imports are removed")
+			DeleteIfExist(ctx, sized)
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Sizing).To(Equal(&ssov1alpha1.RealmSizing{LoginsPerSecond: 20, CachedSessions: 11000}))
+		})
+
+		It("should recommend sizing", func() {
+			key := client.ObjectKeyFromObject(keycloak)
+
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			keycloak.Spec.Instances = &[]int32{2}[0]
+			keycloak.Spec.Sizing = &ssov1alpha1.RealmSizing{LoginsPerSecond: 150}
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Recommendation).NotTo(BeNil())
+			Expect(keycloak.Status.Recommendation.Instances).To(Equal(int32(3)))
+			Expect(keycloak.Status.Recommendation.DatabaseConnections).To(Equal(keycloak.Status.Recommendation.DatabasePoolSize * 3))
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.UnderProvisionedCondition).Status).To(Equal(metav1.ConditionTrue))
+			Expect(keycloak.Status.ConditionMsg(ssov1alpha1.UnderProvisionedCondition)).To(ContainSubstring("5.0 vCPUs per pod exceed 4 vCPUs"))
+
+			By("Clearing the warning once provisioned")
+			keycloak.Spec.Instances = &[]int32{3}[0]
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.UnderProvisionedCondition).Status).To(Equal(metav1.ConditionFalse))
+
+			By("Dropping the recommendation without sizing")
+			keycloak.Spec.Sizing = nil
+			Expect(k8sClient.Update(ctx, keycloak)).To(Succeed())
+			ReconcileKeycloak(ctx, key)
+			Expect(k8sClient.Get(ctx, key, keycloak)).To(Succeed())
+			Expect(keycloak.Status.Recommendation).To(BeNil())
+			Expect(keycloak.Status.GetCondition(ssov1alpha1.UnderProvisionedCondition)).To(BeNil())
+		})
+
 		It("should reconcile changes", func() {
 			key := client.ObjectKeyFromObject(keycloak)
 
@@ -188,10 +1439,47 @@ func GetKeycloakStatefulSet(ctx context.Context, kc *ssov1alpha1.Keycloak) *apps
 	return statefulSet
 }
 
+type fakeDatabaseChecker struct {
+	err     error
+	checked int
+	cfg     *database.Config
+}
+
+func (c *fakeDatabaseChecker) Check(_ context.Context, cfg *database.Config) error {
+	c.checked++
+	c.cfg = cfg
+	return c.err
+}
+
+// NewTestCertificate returns a PEM encoded self-signed certificate
+func NewTestCertificate() string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).NotTo(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "database-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Expect(err).NotTo(HaveOccurred())
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
+
 func ReconcileKeycloak(ctx context.Context, key client.ObjectKey) {
+	ReconcileKeycloakOn(ctx, key, platform.Capabilities{Route: true, ServiceMonitor: true})
+}
+
+func ReconcileKeycloakOn(ctx context.Context, key client.ObjectKey, capabilities platform.Capabilities) {
 	controllerReconciler := &KeycloakReconciler{
-		Client: k8sClient,
-		Scheme: k8sClient.Scheme(),
+		Client:       k8sClient,
+		Scheme:       k8sClient.Scheme(),
+		Capabilities: capabilities,
 	}
 
 	_, err := controllerReconciler.Reconcile(ctx, reconcile.Request{
@@ -0,0 +1,53 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	v14 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ssov1alpha1 "github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/database"
+)
+
+const DatabaseVersionKey = "database"
+
+// reconcileDatabase runs the pre-flight check when the database settings or the referenced secrets
+// changed, or the last check failed
+func (r *KeycloakReconciler) reconcileDatabase(ctx context.Context, cr *ssov1alpha1.Keycloak) error {
+	if r.DatabaseChecker == nil || cr.Spec.Database == nil {
+		return nil
+	}
+
+	// Pool sizes don't affect connectivity
+	settings := cr.Spec.Database.DeepCopy()
+	settings.Pool = nil
+
+	cfg, err := database.NewConfig(ctx, r.APIReader, cr)
+	if err == nil {
+		// A rotated password keeps the spec unchanged, only the version of its secret moves
+		check := struct {
+			Settings *ssov1alpha1.Database
+			Versions map[string]string
+		}{settings, cfg.Versions}
+
+		ready := cr.Status.Conditions.GetCondition(ssov1alpha1.DatabaseReadyCondition)
+		if ready != nil && ready.Status == v14.ConditionTrue && cr.Status.Checks.HasBeenUpdated(DatabaseVersionKey, check) {
+			return nil
+		}
+
+		err = r.DatabaseChecker.Check(ctx, cfg)
+		if err == nil {
+			cr.Status.Checks.UpdateVersion(DatabaseVersionKey, check)
+		}
+	}
+
+	if err != nil {
+		cr.Status.Conditions.UpdateCondition(ssov1alpha1.DatabaseReadyCondition, v14.ConditionFalse, ssov1alpha1.DatabaseReasonConnectionError, err.Error())
+		return err
+	}
+
+	cr.Status.Conditions.UpdateCondition(ssov1alpha1.DatabaseReadyCondition, v14.ConditionTrue, ssov1alpha1.DatabaseReasonConnected, fmt.Sprintf("Connected to %s", cfg.Address()))
+
+	return nil
+}
@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	v12 "github.com/openshift/api/route/v1"
+	v17 "k8s.io/api/networking/v1"
+	v14 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ssov1alpha1 "github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources/rhbk"
+)
+
+// getExposureMode returns the configured exposure, an Ingress is used when requested or routes are not available
+func (r *KeycloakReconciler) getExposureMode(cr *ssov1alpha1.Keycloak) ssov1alpha1.ExposureMode {
+	if cr.Spec.NetworkConfig != nil && cr.Spec.NetworkConfig.Exposure != "" {
+		return cr.Spec.NetworkConfig.Exposure
+	}
+
+	if cr.Spec.Ingress != nil || !r.Capabilities.Route {
+		return ssov1alpha1.ExposureIngress
+	}
+
+	return ssov1alpha1.ExposureRoute
+}
+
+// reconcileExposure exposes Keycloak and removes resources of other exposure modes, it returns the hostname of Keycloak
+func (r *KeycloakReconciler) reconcileExposure(ctx context.Context, cr *ssov1alpha1.Keycloak) (string, error) {
+	var hostname string
+	var err error
+
+	switch r.getExposureMode(cr) {
+	case ssov1alpha1.ExposureRoute:
+		hostname, err = r.reconcileRoute(ctx, cr)
+	case ssov1alpha1.ExposureGateway:
+		hostname, err = r.reconcileGateway(ctx, cr)
+	default:
+		hostname, err = r.reconcileIngress(ctx, cr)
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return hostname, r.cleanupExposure(ctx, cr)
+}
+
+func (r *KeycloakReconciler) reconcileRoute(ctx context.Context, cr *ssov1alpha1.Keycloak) (string, error) {
+	if !r.Capabilities.Route {
+		return "", fmt.Errorf("route API is not available on this cluster")
+	}
+
+	routeResource := rhbk.RHBKRoute{
+		Keycloak: cr,
+		Scheme:   r.Scheme,
+	}
+
+	err := routeResource.CreateOrUpdate(ctx, r.Client)
+	if err != nil {
+		return "", err
+	}
+
+	return routeResource.Resource.Spec.Host, nil
+}
+
+func (r *KeycloakReconciler) reconcileIngress(ctx context.Context, cr *ssov1alpha1.Keycloak) (string, error) {
+	hostname := cr.Spec.GetHostname()
+	if hostname == "" {
+		return "", fmt.Errorf("networkOptions.hostname is required to expose Keycloak through an Ingress")
+	}
+
+	ingressResource := rhbk.RHBKIngress{
+		Keycloak: cr,
+		Scheme:   r.Scheme,
+	}
+
+	return hostname, ingressResource.CreateOrUpdate(ctx, r.Client)
+}
+
+func (r *KeycloakReconciler) reconcileGateway(ctx context.Context, cr *ssov1alpha1.Keycloak) (string, error) {
+	passthrough := cr.Spec.NetworkConfig.Gateway.GetTLSTermination() == ssov1alpha1.GatewayTLSPassthrough
+	if passthrough && !r.Capabilities.TLSRoute {
+		return "", fmt.Errorf("TLSRoute API %s is not available on this cluster", rhbk.TLSRouteGVK.GroupVersion())
+	}
+
+	if !passthrough && (!r.Capabilities.HTTPRoute || !r.Capabilities.BackendTLSPolicy) {
+		return "", fmt.Errorf("HTTPRoute and BackendTLSPolicy APIs %s are required to reencrypt", rhbk.HTTPRouteGVK.GroupVersion())
+	}
+
+	// System CAs don't cover the service CA or private issuers, the gateway could not verify Keycloak
+	if !passthrough && cr.Spec.NetworkConfig.Gateway.BackendCACertificate == nil {
+		return "", fmt.Errorf("networkOptions.gateway.backendCACertificate is required to reencrypt")
+	}
+
+	hostname, err := rhbk.GetGatewayHostname(ctx, r.Client, cr)
+	if err != nil {
+		return "", err
+	}
+
+	routeResource := rhbk.RHBKGatewayRoute{
+		Keycloak: cr,
+		Scheme:   r.Scheme,
+		Hostname: hostname,
+	}
+
+	err = routeResource.CreateOrUpdate(ctx, r.Client)
+	if err != nil || passthrough {
+		return hostname, err
+	}
+
+	policyResource := rhbk.RHBKBackendTLSPolicy{
+		Keycloak: cr,
+		Scheme:   r.Scheme,
+	}
+
+	return hostname, policyResource.CreateOrUpdate(ctx, r.Client)
+}
+
+// cleanupExposure removes resources left over by a previous exposure mode
+func (r *KeycloakReconciler) cleanupExposure(ctx context.Context, cr *ssov1alpha1.Keycloak) error {
+	mode := r.getExposureMode(cr)
+	gatewayMode := ssov1alpha1.GatewayTLSTermination("")
+	if mode == ssov1alpha1.ExposureGateway {
+		gatewayMode = cr.Spec.NetworkConfig.Gateway.GetTLSTermination()
+	}
+
+	var stale []client.Object
+	if mode != ssov1alpha1.ExposureIngress {
+		stale = append(stale, &v17.Ingress{
+			ObjectMeta: v14.ObjectMeta{
+				Name:      rhbk.GetIngressName(cr),
+				Namespace: cr.Namespace,
+			},
+		})
+	}
+
+	if mode != ssov1alpha1.ExposureRoute && r.Capabilities.Route {
+		stale = append(stale, &v12.Route{
+			ObjectMeta: v14.ObjectMeta{
+				Name:      rhbk.GetRouteName(cr),
+				Namespace: cr.Namespace,
+			},
+		})
+	}
+
+	if gatewayMode != ssov1alpha1.GatewayTLSReencrypt {
+		if r.Capabilities.HTTPRoute {
+			stale = append(stale, rhbk.NewUnstructured(rhbk.HTTPRouteGVK, rhbk.GetGatewayRouteName(cr), cr.Namespace))
+		}
+
+		if r.Capabilities.BackendTLSPolicy {
+			stale = append(stale, rhbk.NewUnstructured(rhbk.BackendTLSPolicyGVK, rhbk.GetGatewayRouteName(cr), cr.Namespace))
+		}
+	}
+
+	if gatewayMode != ssov1alpha1.GatewayTLSPassthrough && r.Capabilities.TLSRoute {
+		stale = append(stale, rhbk.NewUnstructured(rhbk.TLSRouteGVK, rhbk.GetGatewayRouteName(cr), cr.Namespace))
+	}
+
+	for _, obj := range stale {
+		err := r.deleteIfOwned(ctx, cr, obj)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteIfOwned deletes a resource controlled by the instance, resources of users sharing the name are left untouched
+func (r *KeycloakReconciler) deleteIfOwned(ctx context.Context, cr *ssov1alpha1.Keycloak, obj client.Object) error {
+	err := r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if !v14.IsControlledBy(obj, cr) {
+		return nil
+	}
+
+	return client.IgnoreNotFound(r.Delete(ctx, obj))
+}
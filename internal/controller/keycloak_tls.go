@@ -0,0 +1,52 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ssov1alpha1 "github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources/rhbk"
+)
+
+// reconcileTLS provides the certificate of the HTTPS endpoint, pods don't start until its secret exists
+func (r *KeycloakReconciler) reconcileTLS(ctx context.Context, cr *ssov1alpha1.Keycloak, hostname string) error {
+	tls := cr.Spec.TLS
+	if tls != nil && tls.IssuerRef != nil {
+		if !r.Capabilities.CertManager {
+			return fmt.Errorf("cert-manager API %s is not available on this cluster", rhbk.CertificateGVK.GroupVersion())
+		}
+
+		certificateResource := rhbk.RHBKCertificate{
+			Keycloak: cr,
+			Scheme:   r.Scheme,
+			Hostname: hostname,
+		}
+
+		return certificateResource.CreateOrUpdate(ctx, r.Client)
+	}
+
+	if r.Capabilities.CertManager {
+		err := r.deleteIfOwned(ctx, cr, rhbk.NewUnstructured(rhbk.CertificateGVK, rhbk.GetCertificateName(cr), cr.Namespace))
+		if err != nil {
+			return err
+		}
+	}
+
+	if tls == nil || r.APIReader == nil {
+		return nil
+	}
+
+	// Secrets provided by users don't carry the watched label of the manager cache
+	err := r.APIReader.Get(ctx, client.ObjectKey{
+		Name:      tls.SecretName,
+		Namespace: cr.Namespace,
+	}, &v1.Secret{})
+	if err != nil {
+		return fmt.Errorf("failed to get TLS secret %s: %w", tls.SecretName, err)
+	}
+
+	return nil
+}
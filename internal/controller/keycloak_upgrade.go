@@ -0,0 +1,137 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	v13 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	v14 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ssov1alpha1 "github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources"
+	"github.com/stakater/rhbk-operator/internal/resources/rhbk"
+)
+
+// reconcileUpgrade decides how the StatefulSet moves to a new image. It reports whether an upgrade is
+// in progress and whether the StatefulSet may be updated, which is not the case while the update
+// compatibility check is running.
+func (r *KeycloakReconciler) reconcileUpgrade(ctx context.Context, cr *ssov1alpha1.Keycloak, current *v13.StatefulSet, statefulSetResource *rhbk.RHBKStatefulSet) (bool, bool, error) {
+	image := rhbk.GetImage(cr)
+	upgrade := cr.Status.Conditions.GetCondition(ssov1alpha1.UpgradeCondition)
+	inProgress := upgrade != nil && upgrade.Status == v14.ConditionTrue
+
+	if rhbk.GetContainerImage(current) == image {
+		if !inProgress {
+			return false, true, nil
+		}
+
+		// Recreate keeps the instance scaled down until every pod of the previous version is gone, pods of the
+		// new version are started by scaling up again and the upgrade completes once they are ready
+		if upgrade.Reason == ssov1alpha1.UpgradeReasonRecreate && current.Spec.Replicas != nil && *current.Spec.Replicas == 0 {
+			if current.Status.Replicas > 0 {
+				statefulSetResource.Replicas = &[]int32{0}[0]
+				return true, true, nil
+			}
+
+			if instances := cr.Spec.GetMinInstances(); instances == nil || *instances > 0 {
+				return true, true, nil
+			}
+		}
+
+		if resources.IsStatefulSetReady(current) && resources.IsStatefulSetRolledOut(current) &&
+			current.Status.ReadyReplicas == *current.Spec.Replicas {
+			cr.Status.Conditions.UpdateCondition(ssov1alpha1.UpgradeCondition, v14.ConditionFalse, ssov1alpha1.UpgradeReasonCompleted, fmt.Sprintf("Upgraded to %s", image))
+			return false, true, r.deleteUpdateCompatibilityJob(ctx, cr)
+		}
+
+		return true, true, nil
+	}
+
+	strategy := cr.Spec.GetUpdateStrategy()
+	if strategy == ssov1alpha1.UpdateStrategyAuto {
+		job, err := r.getUpdateCompatibilityJob(ctx, cr, current, image)
+		if err != nil {
+			return true, false, err
+		}
+
+		switch {
+		case resources.IsJobCompleted(job):
+			strategy = ssov1alpha1.UpdateStrategyRollingUpdate
+		case resources.IsJobFailed(job):
+			failure := resources.GetJobFailure(job)
+			if failure.Reason != v12.JobReasonPodFailurePolicy {
+				cr.Status.Conditions.UpdateCondition(ssov1alpha1.UpgradeCondition, v14.ConditionFalse, ssov1alpha1.UpgradeReasonCheckFailed,
+					fmt.Sprintf("Update compatibility check with %s failed: %s. Set spec.update.strategy to upgrade without the check", image, failure.Message))
+				return true, false, nil
+			}
+
+			strategy = ssov1alpha1.UpdateStrategyRecreate
+		default:
+			cr.Status.Conditions.UpdateCondition(ssov1alpha1.UpgradeCondition, v14.ConditionTrue, ssov1alpha1.UpgradeReasonCompatibilityCheck, fmt.Sprintf("Checking update compatibility with %s", image))
+			return true, false, nil
+		}
+	}
+
+	if strategy == ssov1alpha1.UpdateStrategyRecreate {
+		statefulSetResource.Replicas = &[]int32{0}[0]
+		cr.Status.Conditions.UpdateCondition(ssov1alpha1.UpgradeCondition, v14.ConditionTrue, ssov1alpha1.UpgradeReasonRecreate, fmt.Sprintf("Recreating pods with %s", image))
+	} else {
+		cr.Status.Conditions.UpdateCondition(ssov1alpha1.UpgradeCondition, v14.ConditionTrue, ssov1alpha1.UpgradeReasonRollingUpdate, fmt.Sprintf("Rolling pods to %s", image))
+	}
+
+	return true, true, nil
+}
+
+// getUpdateCompatibilityJob returns the compatibility job for image, a stale job for another image is replaced
+func (r *KeycloakReconciler) getUpdateCompatibilityJob(ctx context.Context, cr *ssov1alpha1.Keycloak, current *v13.StatefulSet, image string) (*v12.Job, error) {
+	job := &v12.Job{}
+	err := r.Get(ctx, client.ObjectKey{
+		Name:      rhbk.GetUpdateCompatibilityJobName(cr),
+		Namespace: cr.Namespace,
+	}, job)
+
+	if client.IgnoreNotFound(err) != nil {
+		return nil, err
+	}
+
+	if err == nil {
+		if job.Annotations[rhbk.UpdateTargetImageAnnotation] == image {
+			return job, nil
+		}
+
+		err = r.Delete(ctx, job, client.PropagationPolicy(v14.DeletePropagationForeground))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	job, err = rhbk.BuildUpdateCompatibilityJob(cr, current, image, r.Scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.Create(ctx, job)
+	if errors.IsAlreadyExists(err) {
+		// Old job is still being deleted, retry on next reconcile
+		return &v12.Job{}, nil
+	}
+
+	return job, err
+}
+
+func (r *KeycloakReconciler) deleteUpdateCompatibilityJob(ctx context.Context, cr *ssov1alpha1.Keycloak) error {
+	job := &v12.Job{}
+	err := r.Get(ctx, client.ObjectKey{
+		Name:      rhbk.GetUpdateCompatibilityJobName(cr),
+		Namespace: cr.Namespace,
+	}, job)
+
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	return client.IgnoreNotFound(r.Delete(ctx, job, client.PropagationPolicy(v14.DeletePropagationForeground)))
+}
@@ -162,7 +162,7 @@ func (r *KeycloakImportReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 
 	// If no job found create job and wait for next reconcile when job is completed
 	if found == nil {
-		importJob, err := realm.Build(cr, statefulSet, importSecret.Resource.ResourceVersion)
+		importJob, err := realm.Build(cr, keycloak, statefulSet, importSecret.Resource.ResourceVersion)
 		if err != nil {
 			return r.HandleError(ctx, cr, err, "Failed to build import job")
 		}
@@ -172,6 +172,8 @@ func (r *KeycloakImportReconciler) Reconcile(ctx context.Context, req ctrl.Reque
 			return ctrl.Result{Requeue: true}, err
 		}
 
+		cr.Status.Resources = importJob.Spec.Template.Spec.Containers[0].Resources.DeepCopy()
+
 		return r.HandleError(ctx, cr, err, "Wait for new import job to be ready")
 	}
 
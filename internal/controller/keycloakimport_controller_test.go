@@ -26,6 +26,7 @@ import (
 	v12 "k8s.io/api/batch/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	kclient "sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
@@ -53,7 +54,7 @@ var _ = Describe("KeycloakImport Controller", func() {
 				},
 				Spec: ssov1alpha1.KeycloakSpec{
 					Instances: &[]int32{1}[0],
-					Database: &ssov1alpha1.PostgresDatabase{
+					Database: &ssov1alpha1.Database{
 						Host: ssov1alpha1.SecretOption{Value: "rhbk-pguser-rhbk"},
 						Port: ssov1alpha1.SecretOption{Value: "5432"},
 					},
@@ -77,6 +78,9 @@ var _ = Describe("KeycloakImport Controller", func() {
 						Name:      keycloak.Name,
 						Namespace: keycloak.Namespace,
 					},
+					Resources: &v1.ResourceRequirements{
+						Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("4Gi")},
+					},
 				},
 			}
 
@@ -159,6 +163,10 @@ var _ = Describe("KeycloakImport Controller", func() {
 			job := GetImportJob(ctx, keycloakImport)
 			Expect(job.Labels).To(HaveKeyWithValue(constants.RHBKImportOwnerLabel, keycloakImport.Name))
 			Expect(job.Labels).To(HaveKeyWithValue(constants.RHBKImportNamespaceLabel, keycloakImport.Namespace))
+			Expect(job.Spec.Template.Spec.Affinity).NotTo(BeNil())
+			Expect(job.Spec.Template.Spec.TopologySpreadConstraints).To(HaveLen(1))
+			Expect(job.Spec.Template.Spec.Containers[0].Resources.Limits).To(HaveKeyWithValue(v1.ResourceMemory, resource.MustParse("4Gi")))
+			Expect(keycloakImport.Status.Resources.Limits).To(HaveKeyWithValue(v1.ResourceMemory, resource.MustParse("4Gi")))
 
 			Expect(keycloakImport.Status.ConditionMsg(apis.ReconcileSuccess)).To(Equal("Wait for new import job to be ready"))
 
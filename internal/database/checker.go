@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+)
+
+const DefaultCheckTimeout = 5 * time.Second
+
+// Checker verifies a Keycloak can reach its database
+type Checker interface {
+	Check(ctx context.Context, cfg *Config) error
+}
+
+// ConnectionChecker dials the database server, PostgreSQL credentials are verified as well
+type ConnectionChecker struct {
+	Timeout time.Duration
+}
+
+func NewConnectionChecker() *ConnectionChecker {
+	return &ConnectionChecker{
+		Timeout: DefaultCheckTimeout,
+	}
+}
+
+func (c *ConnectionChecker) Check(ctx context.Context, cfg *Config) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", cfg.Address())
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", cfg.Address(), err)
+	}
+	_ = conn.Close()
+
+	if cfg.Vendor != v1alpha1.DatabaseVendorPostgres {
+		return nil
+	}
+
+	// Keycloak prefers TLS without verification when not configured
+	if cfg.TLSMode == "" {
+		err = pingPostgres(ctx, cfg, v1alpha1.DatabaseTLSModeRequire)
+		if errors.Is(err, pq.ErrSSLNotSupported) {
+			err = pingPostgres(ctx, cfg, v1alpha1.DatabaseTLSModeDisable)
+		}
+	} else {
+		err = pingPostgres(ctx, cfg, cfg.TLSMode)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to login to %s/%s as %s: %w", cfg.Address(), cfg.Database, cfg.User, err)
+	}
+
+	return nil
+}
+
+func pingPostgres(ctx context.Context, cfg *Config, mode v1alpha1.DatabaseTLSMode) error {
+	options := map[string]string{
+		"host":     cfg.Host,
+		"port":     cfg.Port,
+		"dbname":   cfg.Database,
+		"user":     cfg.User,
+		"password": cfg.Password,
+		"sslmode":  string(mode),
+	}
+
+	if cfg.CA != "" && mode != v1alpha1.DatabaseTLSModeDisable {
+		options["sslinline"] = "true"
+		options["sslrootcert"] = cfg.CA
+	}
+
+	pairs := make([]string, 0, len(options))
+	for k, v := range options {
+		pairs = append(pairs, fmt.Sprintf("%s='%s'", k, escapeDSNValue(v)))
+	}
+
+	connector, err := pq.NewConnector(strings.Join(pairs, " "))
+	if err != nil {
+		return err
+	}
+
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	return db.PingContext(ctx)
+}
+
+func escapeDSNValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v)
+}
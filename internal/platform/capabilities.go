@@ -0,0 +1,101 @@
+package platform
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+)
+
+var routeResource = schema.GroupVersionResource{
+	Group:    "route.openshift.io",
+	Version:  "v1",
+	Resource: "routes",
+}
+
+var serviceMonitorResource = schema.GroupVersionResource{
+	Group:    "monitoring.coreos.com",
+	Version:  "v1",
+	Resource: "servicemonitors",
+}
+
+var httpRouteResource = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "httproutes",
+}
+
+var tlsRouteResource = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1alpha2",
+	Resource: "tlsroutes",
+}
+
+var backendTLSPolicyResource = schema.GroupVersionResource{
+	Group:    "gateway.networking.k8s.io",
+	Version:  "v1",
+	Resource: "backendtlspolicies",
+}
+
+var certificateResource = schema.GroupVersionResource{
+	Group:    "cert-manager.io",
+	Version:  "v1",
+	Resource: "certificates",
+}
+
+// Capabilities lists the optional APIs served by the cluster
+type Capabilities struct {
+	// OpenShift routes
+	Route bool
+	// Prometheus operator service monitors
+	ServiceMonitor bool
+	// Gateway API HTTP routes
+	HTTPRoute bool
+	// Gateway API TLS routes, part of the experimental channel
+	TLSRoute bool
+	// Gateway API backend TLS policies
+	BackendTLSPolicy bool
+	// cert-manager certificates
+	CertManager bool
+}
+
+// Detect queries the API server for optional APIs, it is run once on startup
+func Detect(config *rest.Config) (Capabilities, error) {
+	capabilities := Capabilities{}
+
+	client, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return capabilities, err
+	}
+
+	capabilities.Route, err = discovery.IsResourceEnabled(client, routeResource)
+	if err != nil {
+		return capabilities, err
+	}
+
+	capabilities.ServiceMonitor, err = discovery.IsResourceEnabled(client, serviceMonitorResource)
+	if err != nil {
+		return capabilities, err
+	}
+
+	capabilities.HTTPRoute, err = discovery.IsResourceEnabled(client, httpRouteResource)
+	if err != nil {
+		return capabilities, err
+	}
+
+	capabilities.TLSRoute, err = discovery.IsResourceEnabled(client, tlsRouteResource)
+	if err != nil {
+		return capabilities, err
+	}
+
+	capabilities.BackendTLSPolicy, err = discovery.IsResourceEnabled(client, backendTLSPolicyResource)
+	if err != nil {
+		return capabilities, err
+	}
+
+	capabilities.CertManager, err = discovery.IsResourceEnabled(client, certificateResource)
+	if err != nil {
+		return capabilities, err
+	}
+
+	return capabilities, nil
+}
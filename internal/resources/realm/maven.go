@@ -0,0 +1,104 @@
+package realm
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/constants"
+)
+
+// GetMavenArtifactURL resolves groupId:artifactId:version[:classifier] to the URL of the JAR in a repository
+// laid out like Maven Central, snapshots are not resolved
+func GetMavenArtifactURL(repositoryURL string, coordinates string) (string, error) {
+	parts := strings.Split(coordinates, ":")
+	if len(parts) < 3 || len(parts) > 4 {
+		return "", fmt.Errorf("invalid maven coordinates %s, expected groupId:artifactId:version[:classifier]", coordinates)
+	}
+
+	for _, part := range parts {
+		if part == "" {
+			return "", fmt.Errorf("invalid maven coordinates %s, expected groupId:artifactId:version[:classifier]", coordinates)
+		}
+	}
+
+	group, artifact, version := parts[0], parts[1], parts[2]
+	file := fmt.Sprintf("%s-%s", artifact, version)
+	if len(parts) == 4 {
+		file = fmt.Sprintf("%s-%s", file, parts[3])
+	}
+
+	return fmt.Sprintf("%s/%s/%s/%s/%s.jar",
+		strings.TrimSuffix(repositoryURL, "/"), strings.ReplaceAll(group, ".", "/"), artifact, version, file), nil
+}
+
+func getMavenRepository(cr *v1alpha1.Keycloak, name string) *v1alpha1.MavenRepository {
+	for i := range cr.Spec.MavenRepositories {
+		if cr.Spec.MavenRepositories[i].Name == name {
+			return &cr.Spec.MavenRepositories[i]
+		}
+	}
+
+	return nil
+}
+
+// ValidateProviders checks Maven providers reference a declared repository with valid coordinates
+func ValidateProviders(cr *v1alpha1.Keycloak) error {
+	for _, p := range cr.Spec.Providers {
+		if p.Maven == nil {
+			continue
+		}
+
+		repository := getMavenRepository(cr, p.Maven.Repository)
+		if repository == nil {
+			return fmt.Errorf("maven repository %s of provider %s is not declared in spec.mavenRepositories", p.Maven.Repository, p.Name)
+		}
+
+		if _, err := GetMavenArtifactURL(repository.URL, p.Maven.Coordinates); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getMavenDownload returns the environment and the command downloading a Maven provider,
+// credentials are read from the environment by the shell so they don't appear in the pod spec
+func getMavenDownload(cr *v1alpha1.Keycloak, p v1alpha1.Provider) ([]v1.EnvVar, string) {
+	repository := getMavenRepository(cr, p.Maven.Repository)
+	if repository == nil {
+		return nil, ""
+	}
+
+	url, err := GetMavenArtifactURL(repository.URL, p.Maven.Coordinates)
+	if err != nil {
+		return nil, ""
+	}
+
+	envName := ConvertToEnvName(p.Name)
+	env := []v1.EnvVar{{Name: envName, Value: url}}
+	cmd := fmt.Sprintf("curl -LJ --fail --show-error --cacert %s/ca-bundle.crt", constants.TrustedCaVolumeMountPath)
+
+	if repository.Proxy != "" {
+		cmd += fmt.Sprintf(" --proxy %s", repository.Proxy)
+	}
+
+	if repository.CredentialsSecret != nil {
+		userEnv := ConvertToEnvName(fmt.Sprintf("maven_%s_username", repository.Name))
+		passwordEnv := ConvertToEnvName(fmt.Sprintf("maven_%s_password", repository.Name))
+		for _, credential := range [][2]string{{userEnv, "username"}, {passwordEnv, "password"}} {
+			env = append(env, v1.EnvVar{
+				Name: credential[0],
+				ValueFrom: &v1.EnvVarSource{
+					SecretKeyRef: &v1.SecretKeySelector{LocalObjectReference: *repository.CredentialsSecret, Key: credential[1]},
+				},
+			})
+		}
+
+		cmd += fmt.Sprintf(` -u "${%s}:${%s}"`, userEnv, passwordEnv)
+	}
+
+	return env, fmt.Sprintf("%s -o %s/%s $(%s)", cmd, ProvidersPATH, p.Name, envName)
+}
@@ -0,0 +1,102 @@
+package realm
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetMavenArtifactURL(t *testing.T) {
+	// Repository laid out like Maven Central behind basic auth
+	artifacts := map[string]string{
+		"/maven-releases/io/phasetwo/keycloak/keycloak-events/0.30/keycloak-events-0.30.jar":     "events",
+		"/maven-releases/com/acme/iam/acme-spi/1.2.0/acme-spi-1.2.0-shaded.jar":                  "shaded",
+		"/maven-releases/org/keycloak/keycloak-metrics-spi/6.0.0/keycloak-metrics-spi-6.0.0.jar": "metrics",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, password, ok := r.BasicAuth(); !ok || user != "deployer" || password != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		content, ok := artifacts[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = io.WriteString(w, content)
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name        string
+		repository  string
+		coordinates string
+		want        string
+		wantErr     bool
+	}{
+		{
+			name:        "release",
+			repository:  server.URL + "/maven-releases",
+			coordinates: "io.phasetwo.keycloak:keycloak-events:0.30",
+			want:        "events",
+		},
+		{
+			name:        "classifier",
+			repository:  server.URL + "/maven-releases",
+			coordinates: "com.acme.iam:acme-spi:1.2.0:shaded",
+			want:        "shaded",
+		},
+		{
+			name:        "trailing slash of repository",
+			repository:  server.URL + "/maven-releases/",
+			coordinates: "org.keycloak:keycloak-metrics-spi:6.0.0",
+			want:        "metrics",
+		},
+		{
+			name:        "missing version",
+			repository:  server.URL + "/maven-releases",
+			coordinates: "org.keycloak:keycloak-metrics-spi",
+			wantErr:     true,
+		},
+		{
+			name:        "empty artifact",
+			repository:  server.URL + "/maven-releases",
+			coordinates: "org.keycloak::6.0.0",
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, err := GetMavenArtifactURL(tt.repository, tt.coordinates)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetMavenArtifactURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			req, err := http.NewRequest(http.MethodGet, url, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.SetBasicAuth("deployer", "secret")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != http.StatusOK || string(body) != tt.want {
+				t.Errorf("GET %s = %d %q, want %q", url, resp.StatusCode, body, tt.want)
+			}
+		})
+	}
+}
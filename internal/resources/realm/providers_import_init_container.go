@@ -2,28 +2,68 @@ package realm
 
 import (
 	"fmt"
+	"os"
+	"path"
 	"regexp"
 	"strings"
 	"unicode"
 
 	"github.com/stakater/rhbk-operator/internal/constants"
+	"github.com/stakater/rhbk-operator/internal/resources"
 
 	"github.com/stakater/rhbk-operator/api/v1alpha1"
 	v1 "k8s.io/api/core/v1"
 )
 
 const BusyboxImage = "registry.access.redhat.com/ubi8/ubi:8.10-1088"
+const BusyboxImageEnv = "RELATED_IMAGE_UBI"
 const ProvidersPATH = "/opt/keycloak/providers"
 
+const FetchContainerName = "fetch"
+
+const ProviderCacheVolume = "provider-cache"
+const ProviderCachePATH = "/mnt/provider-cache"
+
+// ChecksumMismatchMessage is written to the termination message of the fetch container on a mismatch
+const ChecksumMismatchMessage = "checksum mismatch for provider"
+
+// GetBusyboxImage returns the utility image used by init containers,
+// RELATED_IMAGE_UBI overrides the default for disconnected mirrors.
+func GetBusyboxImage() string {
+	if image := os.Getenv(BusyboxImageEnv); image != "" {
+		return image
+	}
+
+	return BusyboxImage
+}
+
+// GetInitContainer returns the containers fetching the providers, JARs are downloaded by a single container
+// and every image is copied by its own container
 func GetInitContainer(cr *v1alpha1.Keycloak) []v1.Container {
-	if len(cr.Spec.Providers) == 0 {
-		return nil
+	var containers []v1.Container
+	var downloads []v1alpha1.Provider
+	for _, p := range cr.Spec.Providers {
+		if p.Image != nil {
+			mount := v1.VolumeMount{Name: "providers", MountPath: ProvidersPATH}
+			containers = append(containers, NewImageCopyContainer(GetContainerName("provider", p.Name), p.Image, mount, ProvidersPATH))
+		} else {
+			downloads = append(downloads, p)
+		}
+	}
+
+	if len(downloads) == 0 {
+		return containers
 	}
 
-	runArg := fmt.Sprintf("mkdir -p %s; curl -LJ --show-error --cacert %s/ca-bundle.crt", ProvidersPATH, constants.TrustedCaVolumeMountPath)
+	return append([]v1.Container{getDownloadContainer(cr, downloads)}, containers...)
+}
+
+func getDownloadContainer(cr *v1alpha1.Keycloak, providers []v1alpha1.Provider) v1.Container {
+	// Any failed download fails the pod, only writes to the cache may fail
+	runArg := fmt.Sprintf("set -eo pipefail; mkdir -p %s", ProvidersPATH)
 	downloadContainer := v1.Container{
-		Name:  "fetch",
-		Image: BusyboxImage,
+		Name:  FetchContainerName,
+		Image: GetBusyboxImage(),
 		Env:   []v1.EnvVar{},
 		Command: []string{
 			"/bin/bash",
@@ -43,34 +83,167 @@ func GetInitContainer(cr *v1alpha1.Keycloak) []v1.Container {
 		},
 	}
 
-	for _, p := range cr.Spec.Providers {
-		envName := ConvertToEnvName(p.Name)
-		if p.URL.Value != "" {
-			downloadContainer.Env = append(downloadContainer.Env, v1.EnvVar{
-				Name:  envName,
-				Value: p.URL.Value,
-			})
+	cache := cr.Spec.ProviderCache != nil
+	if cache {
+		downloadContainer.VolumeMounts = append(downloadContainer.VolumeMounts, v1.VolumeMount{
+			Name:      ProviderCacheVolume,
+			MountPath: ProviderCachePATH,
+		})
+	}
+
+	// URLs are downloaded by a single curl, Maven repositories each need their own options.
+	// Cached providers are downloaded on their own when the cached file does not match.
+	var urlArgs string
+	var mavenArgs []string
+	var cachedArgs []string
+	for _, p := range providers {
+		var arg string
+		if p.Maven != nil {
+			var env []v1.EnvVar
+			env, arg = getMavenDownload(cr, p)
+			for _, e := range env {
+				downloadContainer.Env = resources.AddOrReplaceEnv(e, downloadContainer.Env)
+			}
 		} else {
-			downloadContainer.Env = append(downloadContainer.Env, v1.EnvVar{
-				Name: envName,
-				ValueFrom: &v1.EnvVarSource{
-					SecretKeyRef: p.URL.Secret,
-				},
-			})
+			envName := ConvertToEnvName(p.Name)
+			downloadContainer.Env = append(downloadContainer.Env, getOptionEnv(envName, p.URL))
+
+			if !cache || !p.SHA256.IsSet() {
+				urlArgs += fmt.Sprintf(" -o %s/%s $(%s)", ProvidersPATH, p.Name, envName)
+				continue
+			}
+
+			arg = fmt.Sprintf("curl -LJ --fail --show-error --cacert %s/ca-bundle.crt -o %s/%s $(%s)",
+				constants.TrustedCaVolumeMountPath, ProvidersPATH, p.Name, envName)
+		}
+
+		if cache && p.SHA256.IsSet() {
+			cached := getCachedProvider(p)
+			cachedArgs = append(cachedArgs, fmt.Sprintf("{ %s && cp %s %s/%s || %s; }",
+				getCacheCheck(p), cached, ProvidersPATH, p.Name, arg))
+			continue
+		}
+
+		mavenArgs = append(mavenArgs, arg)
+	}
+
+	if urlArgs != "" {
+		// Without --fail-early curl returns the status of the last transfer only
+		runArg += fmt.Sprintf("; curl -LJ --fail --fail-early --show-error --cacert %s/ca-bundle.crt%s", constants.TrustedCaVolumeMountPath, urlArgs)
+	}
+
+	// Downloads are separate commands, set -e ignores failures inside && lists
+	for _, arg := range mavenArgs {
+		runArg += fmt.Sprintf("; %s", arg)
+	}
+
+	for _, arg := range cachedArgs {
+		runArg += fmt.Sprintf("; %s", arg)
+	}
+
+	// Checksums are verified once every JAR is downloaded
+	for _, p := range providers {
+		if !p.SHA256.IsSet() {
+			continue
 		}
 
-		runArg += fmt.Sprintf(" -o %s/%s $(%s)", ProvidersPATH, p.Name, envName)
+		envName := ConvertToEnvName(p.Name + "_sha256")
+		downloadContainer.Env = append(downloadContainer.Env, getOptionEnv(envName, p.SHA256))
+		runArg += fmt.Sprintf("; echo \"$(%s)  %s/%s\" | sha256sum -c --status || { echo '%s %s' > %s; exit 1; }",
+			envName, ProvidersPATH, p.Name, ChecksumMismatchMessage, p.Name, v1.TerminationMessagePathDefault)
+	}
+
+	// Verified providers are stored in the cache, a full or read-only cache does not fail the pod
+	for _, p := range providers {
+		if !cache || !p.SHA256.IsSet() {
+			continue
+		}
+
+		cached := getCachedProvider(p)
+		tmp := fmt.Sprintf("%s/.%s.$HOSTNAME", path.Dir(cached), path.Base(cached))
+		runArg += fmt.Sprintf("; %s || { mkdir -p %s && cp %s/%s %s && mv %s %s; } || true",
+			getCacheCheck(p), path.Dir(cached), ProvidersPATH, p.Name, tmp, tmp, cached)
 	}
 
 	downloadContainer.Args = []string{
 		"-c",
 		runArg,
 	}
-	return []v1.Container{
-		downloadContainer,
+	return downloadContainer
+}
+
+// getCachedProvider returns the path of a provider in the cache, keyed by its name and checksum
+func getCachedProvider(p v1alpha1.Provider) string {
+	return fmt.Sprintf("%s/%s/$(%s)", ProviderCachePATH, p.Name, ConvertToEnvName(p.Name+"_sha256"))
+}
+
+// getCacheCheck returns the command succeeding when the cached provider matches its checksum
+func getCacheCheck(p v1alpha1.Provider) string {
+	return fmt.Sprintf("echo \"$(%s)  %s\" | sha256sum -c --status 2>/dev/null",
+		ConvertToEnvName(p.Name+"_sha256"), getCachedProvider(p))
+}
+
+func getOptionEnv(name string, option v1alpha1.SecretOption) v1.EnvVar {
+	if option.Value != "" {
+		return v1.EnvVar{
+			Name:  name,
+			Value: option.Value,
+		}
+	}
+
+	return v1.EnvVar{
+		Name: name,
+		ValueFrom: &v1.EnvVarSource{
+			SecretKeyRef: option.Secret,
+		},
 	}
 }
 
+// GetChecksumMismatch returns the termination message of a fetch container failing verification, empty without any
+func GetChecksumMismatch(pods []v1.Pod) string {
+	for _, pod := range pods {
+		for _, status := range pod.Status.InitContainerStatuses {
+			// A later successful run supersedes the last termination
+			if status.Name != FetchContainerName || (status.State.Terminated != nil && status.State.Terminated.ExitCode == 0) {
+				continue
+			}
+
+			for _, state := range []v1.ContainerState{status.State, status.LastTerminationState} {
+				if state.Terminated != nil && strings.HasPrefix(state.Terminated.Message, ChecksumMismatchMessage) {
+					return strings.TrimSpace(state.Terminated.Message)
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+// NewImageCopyContainer copies the directory of an image into target, a directory on the mounted volume
+func NewImageCopyContainer(name string, source *v1alpha1.ImageSource, mount v1.VolumeMount, target string) v1.Container {
+	return v1.Container{
+		Name:            name,
+		Image:           source.Image,
+		ImagePullPolicy: source.PullPolicy,
+		Command: []string{
+			"/bin/sh",
+		},
+		Args: []string{
+			"-c",
+			fmt.Sprintf("mkdir -p %[2]s && cp -R %[1]s/. %[2]s/", strings.TrimSuffix(source.Path, "/"), target),
+		},
+		VolumeMounts: []v1.VolumeMount{mount},
+	}
+}
+
+// GetContainerName returns a valid container or volume name for an arbitrary name such as a file name
+func GetContainerName(prefix string, name string) string {
+	re := regexp.MustCompile(`[^a-z0-9-]+`)
+	sanitized := strings.Trim(re.ReplaceAllString(strings.ToLower(name), "-"), "-")
+
+	return strings.TrimSuffix(fmt.Sprintf("%.63s", fmt.Sprintf("%s-%s", prefix, sanitized)), "-")
+}
+
 func ConvertToEnvName(input string) string {
 	re := regexp.MustCompile(`[^a-zA-Z0-9_]`)
 	sanitized := re.ReplaceAllString(input, "_")
@@ -0,0 +1,234 @@
+package realm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/constants"
+)
+
+var envReference = regexp.MustCompile(`\$\(([A-Za-z_][A-Za-z0-9_]*)\)`)
+
+// runFetchContainer runs the script of the fetch container like the kubelet would, with the volumes in dir
+func runFetchContainer(t *testing.T, cr *v1alpha1.Keycloak, dir string) (string, error) {
+	t.Helper()
+
+	container := GetInitContainer(cr)[0]
+	env := map[string]string{}
+	for _, e := range container.Env {
+		env[e.Name] = e.Value
+	}
+
+	script := envReference.ReplaceAllStringFunc(container.Args[1], func(ref string) string {
+		return env[envReference.FindStringSubmatch(ref)[1]]
+	})
+
+	script = strings.NewReplacer(
+		ProvidersPATH, filepath.Join(dir, "providers"),
+		ProviderCachePATH, filepath.Join(dir, "cache"),
+		constants.TrustedCaVolumeMountPath+"/", dir+"/",
+		v1.TerminationMessagePathDefault, filepath.Join(dir, "termination-log"),
+	).Replace(script)
+
+	out, err := exec.Command("bash", "-c", script).CombinedOutput()
+	return string(out), err
+}
+
+func TestFetchContainer(t *testing.T) {
+	for _, tool := range []string{"bash", "curl", "sha256sum"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not available", tool)
+		}
+	}
+
+	artifacts := map[string]string{
+		"/files/events.jar":  "events",
+		"/files/metrics.jar": "metrics",
+		"/maven/io/phasetwo/keycloak/keycloak-events/0.30/keycloak-events-0.30.jar": "events",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := artifacts[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_, _ = io.WriteString(w, content)
+	}))
+	defer server.Close()
+
+	checksum := func(content string) v1alpha1.SecretOption {
+		sum := sha256.Sum256([]byte(content))
+		return v1alpha1.SecretOption{Value: hex.EncodeToString(sum[:])}
+	}
+
+	url := func(path string) v1alpha1.SecretOption {
+		return v1alpha1.SecretOption{Value: server.URL + path}
+	}
+
+	maven := func(coordinates string) *v1alpha1.MavenArtifact {
+		return &v1alpha1.MavenArtifact{Coordinates: coordinates, Repository: "nexus"}
+	}
+
+	tests := []struct {
+		name      string
+		providers []v1alpha1.Provider
+		cache     bool
+		want      map[string]string
+		wantErr   bool
+	}{
+		{
+			name: "urls",
+			providers: []v1alpha1.Provider{
+				{Name: "events.jar", URL: url("/files/events.jar")},
+				{Name: "metrics.jar", URL: url("/files/metrics.jar"), SHA256: checksum("metrics")},
+			},
+			want: map[string]string{"events.jar": "events", "metrics.jar": "metrics"},
+		},
+		{
+			name: "first url fails",
+			providers: []v1alpha1.Provider{
+				{Name: "missing.jar", URL: url("/files/missing.jar")},
+				{Name: "metrics.jar", URL: url("/files/metrics.jar")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "maven",
+			providers: []v1alpha1.Provider{
+				{Name: "events.jar", Maven: maven("io.phasetwo.keycloak:keycloak-events:0.30"), SHA256: checksum("events")},
+				{Name: "metrics.jar", URL: url("/files/metrics.jar")},
+			},
+			want: map[string]string{"events.jar": "events", "metrics.jar": "metrics"},
+		},
+		{
+			name: "first maven fails",
+			providers: []v1alpha1.Provider{
+				{Name: "missing.jar", Maven: maven("io.phasetwo.keycloak:keycloak-missing:0.30")},
+				{Name: "events.jar", Maven: maven("io.phasetwo.keycloak:keycloak-events:0.30")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "maven fails before verified provider",
+			providers: []v1alpha1.Provider{
+				{Name: "missing.jar", Maven: maven("io.phasetwo.keycloak:keycloak-missing:0.30")},
+				{Name: "events.jar", Maven: maven("io.phasetwo.keycloak:keycloak-events:0.30")},
+				{Name: "metrics.jar", URL: url("/files/metrics.jar"), SHA256: checksum("metrics")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "checksum mismatch",
+			providers: []v1alpha1.Provider{
+				{Name: "metrics.jar", URL: url("/files/metrics.jar"), SHA256: checksum("tampered")},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cached provider fails before other provider",
+			providers: []v1alpha1.Provider{
+				{Name: "missing.jar", URL: url("/files/missing.jar"), SHA256: checksum("missing")},
+				{Name: "metrics.jar", URL: url("/files/metrics.jar")},
+			},
+			cache:   true,
+			wantErr: true,
+		},
+		{
+			name: "cached provider",
+			providers: []v1alpha1.Provider{
+				{Name: "events.jar", Maven: maven("io.phasetwo.keycloak:keycloak-events:0.30"), SHA256: checksum("events")},
+				{Name: "metrics.jar", URL: url("/files/metrics.jar"), SHA256: checksum("metrics")},
+			},
+			cache: true,
+			want:  map[string]string{"events.jar": "events", "metrics.jar": "metrics"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr := &v1alpha1.Keycloak{}
+			cr.Spec.Providers = tt.providers
+			cr.Spec.MavenRepositories = []v1alpha1.MavenRepository{{Name: "nexus", URL: server.URL + "/maven"}}
+			if tt.cache {
+				cr.Spec.ProviderCache = &v1alpha1.ProviderCacheSpec{}
+			}
+
+			dir := t.TempDir()
+			out, err := runFetchContainer(t, cr, dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("fetch container error = %v, wantErr %v\n%s", err, tt.wantErr, out)
+			}
+
+			for name, content := range tt.want {
+				data, err := os.ReadFile(filepath.Join(dir, "providers", name))
+				if err != nil || string(data) != content {
+					t.Errorf("provider %s = %q, %v, want %q", name, data, err, content)
+				}
+			}
+		})
+	}
+}
+
+func TestFetchContainerCache(t *testing.T) {
+	for _, tool := range []string{"bash", "curl", "sha256sum"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s is not available", tool)
+		}
+	}
+
+	up := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_, _ = io.WriteString(w, "metrics")
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256([]byte("metrics"))
+	cr := &v1alpha1.Keycloak{}
+	cr.Spec.ProviderCache = &v1alpha1.ProviderCacheSpec{}
+	cr.Spec.Providers = []v1alpha1.Provider{
+		{
+			Name:   "metrics.jar",
+			URL:    v1alpha1.SecretOption{Value: server.URL + "/metrics.jar"},
+			SHA256: v1alpha1.SecretOption{Value: hex.EncodeToString(sum[:])},
+		},
+	}
+
+	dir := t.TempDir()
+	if out, err := runFetchContainer(t, cr, dir); err != nil {
+		t.Fatalf("fetch container error = %v\n%s", err, out)
+	}
+
+	// A restarted pod starts from the cache while the server is down
+	up = false
+	if err := os.RemoveAll(filepath.Join(dir, "providers")); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, err := runFetchContainer(t, cr, dir); err != nil {
+		t.Fatalf("fetch container error = %v\n%s", err, out)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "providers", "metrics.jar"))
+	if err != nil || string(data) != "metrics" {
+		t.Errorf("provider metrics.jar = %q, %v, want %q", data, err, "metrics")
+	}
+}
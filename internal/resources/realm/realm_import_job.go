@@ -3,6 +3,7 @@ package realm
 import (
 	"context"
 	"fmt"
+	"maps"
 
 	"k8s.io/apimachinery/pkg/labels"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,13 +38,11 @@ type RealmImportJob struct {
 	StatefulSet    *v1.StatefulSet
 }
 
-func Build(cr *v1alpha1.KeycloakImport, sts *v1.StatefulSet, revision string) (*v12.Job, error) {
-	ownerLabels := resources.GetOwnerLabels(cr.Name, cr.Namespace)
-	ownerLabels[GetImportJobAnnotation(cr)] = revision
-	resources.DecorateDefaultLabels(ownerLabels)
-
+// NewJobTemplate copies the RHBK pod template of the StatefulSet into a template fit for one-off jobs,
+// it keeps the scheduling and the unsupported overlay of the pods and runs with a local cache, without probes and shutdown delay.
+func NewJobTemplate(sts *v1.StatefulSet, labels map[string]string) *v14.PodTemplateSpec {
 	template := sts.Spec.Template.DeepCopy()
-	template.Labels = ownerLabels
+	template.Labels = labels
 	kcContainer := &template.Spec.Containers[0]
 
 	toModify := map[string]string{
@@ -69,6 +68,35 @@ func Build(cr *v1alpha1.KeycloakImport, sts *v1.StatefulSet, revision string) (*
 		}
 	}
 
+	kcContainer.Env = next
+
+	// Remove probes and the shutdown delay, jobs are not behind a router
+	kcContainer.ReadinessProbe = nil
+	kcContainer.LivenessProbe = nil
+	kcContainer.StartupProbe = nil
+	kcContainer.Lifecycle = nil
+
+	template.Spec.RestartPolicy = v14.RestartPolicyNever
+
+	return template
+}
+
+func Build(cr *v1alpha1.KeycloakImport, kc *v1alpha1.Keycloak, sts *v1.StatefulSet, revision string) (*v12.Job, error) {
+	ownerLabels := resources.GetOwnerLabels(cr.Name, cr.Namespace)
+	ownerLabels[GetImportJobAnnotation(cr)] = revision
+	resources.DecorateDefaultLabels(ownerLabels)
+
+	// The copied template has the overlay, except for the labels replaced by the owner labels
+	templateLabels := map[string]string{}
+	if overlay := kc.Spec.GetPodTemplate(); overlay != nil {
+		maps.Copy(templateLabels, overlay.Labels)
+	}
+	maps.Copy(templateLabels, ownerLabels)
+
+	template := NewJobTemplate(sts, templateLabels)
+	kcContainer := &template.Spec.Containers[0]
+	kcContainer.Resources = resources.MergeResourceRequirements(kcContainer.Resources, cr.Spec.Resources)
+
 	// Setup volume for mounting realm JSON
 	template.Spec.Volumes = append(template.Spec.Volumes, v14.Volume{
 		Name: GetImportJobSecretVolumeName(cr),
@@ -85,13 +113,6 @@ func Build(cr *v1alpha1.KeycloakImport, sts *v1.StatefulSet, revision string) (*
 		MountPath: "/mnt/realm-import",
 	})
 
-	kcContainer.Env = next
-
-	// Remove probes
-	kcContainer.ReadinessProbe = nil
-	kcContainer.LivenessProbe = nil
-	kcContainer.StartupProbe = nil
-
 	cmd := []string{
 		"/bin/bash",
 	}
@@ -108,7 +129,6 @@ func Build(cr *v1alpha1.KeycloakImport, sts *v1.StatefulSet, revision string) (*
 	kcContainer.Command = cmd
 	kcContainer.Args = args
 
-	template.Spec.RestartPolicy = v14.RestartPolicyNever
 	job := &v12.Job{
 		ObjectMeta: v13.ObjectMeta{
 			Name:      GetImportJobName(cr),
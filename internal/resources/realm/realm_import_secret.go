@@ -45,17 +45,11 @@ func (s *ImportRealmSecret) CreateOrUpdate(ctx context.Context, c client.Client)
 	// Fetch substitutions
 	s.substitutions = make(map[string]string)
 	for _, sub := range s.ImportCR.Spec.Substitutions {
-		secret := &v1.Secret{}
-		err := c.Get(ctx, client.ObjectKey{
-			Name:      sub.Secret.Name,
-			Namespace: s.ImportCR.Namespace,
-		}, secret)
-
+		value, err := s.getSubstitution(ctx, c, sub)
 		if err != nil {
 			return err
 		}
 
-		value := string(secret.Data[sub.Secret.Key])
 		escapedValue, err := resources.EscapeString(value)
 		if err != nil {
 			return fmt.Errorf("failed to escape value for key %s: %w", sub.Name, err)
@@ -67,6 +61,38 @@ func (s *ImportRealmSecret) CreateOrUpdate(ctx context.Context, c client.Client)
 	return err
 }
 
+// getSubstitution returns the literal value of a substitution, or the key of its Secret or ConfigMap
+func (s *ImportRealmSecret) getSubstitution(ctx context.Context, c client.Client, sub v1alpha1.SecretOptionVar) (string, error) {
+	switch {
+	case sub.Secret != nil:
+		secret := &v1.Secret{}
+		err := c.Get(ctx, client.ObjectKey{
+			Name:      sub.Secret.Name,
+			Namespace: s.ImportCR.Namespace,
+		}, secret)
+
+		if err != nil {
+			return "", err
+		}
+
+		return string(secret.Data[sub.Secret.Key]), nil
+	case sub.ConfigMap != nil:
+		cm := &v1.ConfigMap{}
+		err := c.Get(ctx, client.ObjectKey{
+			Name:      sub.ConfigMap.Name,
+			Namespace: s.ImportCR.Namespace,
+		}, cm)
+
+		if err != nil {
+			return "", err
+		}
+
+		return cm.Data[sub.ConfigMap.Key], nil
+	default:
+		return sub.Value, nil
+	}
+}
+
 func (s *ImportRealmSecret) MutateFn() error {
 	realm, err := expandTemplate(s.ImportCR.Spec.JSON, s.substitutions)
 	if err != nil {
@@ -0,0 +1,114 @@
+package rhbk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources"
+)
+
+// cert-manager is optional, certificates are handled unstructured
+var CertificateGVK = schema.GroupVersionKind{Group: "cert-manager.io", Version: "v1", Kind: "Certificate"}
+
+const DefaultCertificateReloadPeriod = time.Hour
+
+func GetCertificateName(cr *v1alpha1.Keycloak) string {
+	return cr.Name
+}
+
+// GetCertificateReloadPeriod returns the reload period in the duration format of Keycloak
+func GetCertificateReloadPeriod(cr *v1alpha1.Keycloak) string {
+	period := DefaultCertificateReloadPeriod
+	if cr.Spec.TLS != nil && cr.Spec.TLS.ReloadPeriod != nil {
+		period = cr.Spec.TLS.ReloadPeriod.Duration
+	}
+
+	return fmt.Sprintf("%ds", int64(period.Seconds()))
+}
+
+// GetServiceDNSNames returns the names the Keycloak service is reached by inside the cluster
+func GetServiceDNSNames(cr *v1alpha1.Keycloak) []string {
+	svc := GetSvcName(cr)
+	return []string{
+		svc,
+		fmt.Sprintf("%s.%s", svc, cr.Namespace),
+		fmt.Sprintf("%s.%s.svc", svc, cr.Namespace),
+		fmt.Sprintf("%s.%s.svc.cluster.local", svc, cr.Namespace),
+	}
+}
+
+// RHBKCertificate requests the certificate of the HTTPS endpoint from a cert-manager issuer
+type RHBKCertificate struct {
+	Keycloak *v1alpha1.Keycloak
+	Scheme   *runtime.Scheme
+	// External hostname, not part of the certificate when empty
+	Hostname string
+	Resource *unstructured.Unstructured
+}
+
+func (s *RHBKCertificate) Build() error {
+	defaultLabels := map[string]string{}
+	resources.DecorateDefaultLabels(defaultLabels)
+
+	issuer := s.Keycloak.Spec.TLS.IssuerRef
+	kind := issuer.Kind
+	if kind == "" {
+		kind = "Issuer"
+	}
+
+	group := issuer.Group
+	if group == "" {
+		group = CertificateGVK.Group
+	}
+
+	var dnsNames []interface{}
+	for _, name := range GetServiceDNSNames(s.Keycloak) {
+		dnsNames = append(dnsNames, name)
+	}
+
+	if s.Hostname != "" {
+		dnsNames = append(dnsNames, s.Hostname)
+	}
+
+	s.Resource.SetLabels(defaultLabels)
+	s.Resource.Object["spec"] = map[string]interface{}{
+		"secretName": GetTLSSecretName(s.Keycloak),
+		"commonName": fmt.Sprintf("%s.%s.svc", GetSvcName(s.Keycloak), s.Keycloak.Namespace),
+		"dnsNames":   dnsNames,
+		"usages":     []interface{}{"server auth", "digital signature", "key encipherment"},
+		"issuerRef": map[string]interface{}{
+			"name":  issuer.Name,
+			"kind":  kind,
+			"group": group,
+		},
+		"secretTemplate": map[string]interface{}{
+			"labels": toInterfaceMap(defaultLabels),
+		},
+	}
+
+	return controllerutil.SetControllerReference(s.Keycloak, s.Resource, s.Scheme)
+}
+
+func (s *RHBKCertificate) CreateOrUpdate(ctx context.Context, c client.Client) error {
+	s.Resource = NewUnstructured(CertificateGVK, GetCertificateName(s.Keycloak), s.Keycloak.Namespace)
+	_, err := controllerutil.CreateOrUpdate(ctx, c, s.Resource, s.Build)
+
+	return err
+}
+
+func toInterfaceMap(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+
+	return out
+}
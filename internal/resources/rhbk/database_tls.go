@@ -0,0 +1,209 @@
+package rhbk
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strings"
+
+	v12 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+)
+
+const DatabaseCAVolume = "database-ca"
+const DatabaseCAPath = "/mnt/database-ca"
+const DatabaseCAKey = "ca.crt"
+const DatabaseCAFile = DatabaseCAPath + "/" + DatabaseCAKey
+
+type urlParameter struct {
+	Name  string
+	Value string
+}
+
+func getDatabaseCA(cr *v1alpha1.Keycloak) *v1alpha1.CertificateSource {
+	db := cr.Spec.Database
+	if db == nil || db.TLS == nil {
+		return nil
+	}
+
+	return db.TLS.CA
+}
+
+// getDatabaseTLSParameters translates the TLS mode into the JDBC parameters of the vendor driver.
+// Drivers without PEM support read the CA from the Keycloak truststore.
+func getDatabaseTLSParameters(db *v1alpha1.Database) []urlParameter {
+	if db.TLS == nil {
+		return nil
+	}
+
+	mode := db.TLS.GetMode()
+	hasCA := db.TLS.CA != nil
+
+	switch db.GetVendor() {
+	case v1alpha1.DatabaseVendorMySQL:
+		return []urlParameter{{"sslMode", map[v1alpha1.DatabaseTLSMode]string{
+			v1alpha1.DatabaseTLSModeDisable:    "DISABLED",
+			v1alpha1.DatabaseTLSModeRequire:    "REQUIRED",
+			v1alpha1.DatabaseTLSModeVerifyCA:   "VERIFY_CA",
+			v1alpha1.DatabaseTLSModeVerifyFull: "VERIFY_IDENTITY",
+		}[mode]}}
+	case v1alpha1.DatabaseVendorMariaDB:
+		params := []urlParameter{{"sslMode", map[v1alpha1.DatabaseTLSMode]string{
+			v1alpha1.DatabaseTLSModeDisable:    "disable",
+			v1alpha1.DatabaseTLSModeRequire:    "trust",
+			v1alpha1.DatabaseTLSModeVerifyCA:   "verify-ca",
+			v1alpha1.DatabaseTLSModeVerifyFull: "verify-full",
+		}[mode]}}
+		if hasCA && mode != v1alpha1.DatabaseTLSModeDisable {
+			params = append(params, urlParameter{"serverSslCert", DatabaseCAFile})
+		}
+
+		return params
+	case v1alpha1.DatabaseVendorMSSQL:
+		switch mode {
+		case v1alpha1.DatabaseTLSModeDisable:
+			return []urlParameter{{"encrypt", "false"}}
+		case v1alpha1.DatabaseTLSModeRequire:
+			return []urlParameter{{"encrypt", "true"}, {"trustServerCertificate", "true"}}
+		default:
+			return []urlParameter{{"encrypt", "true"}, {"trustServerCertificate", "false"}}
+		}
+	default:
+		params := []urlParameter{{"sslmode", string(mode)}}
+		if hasCA && mode != v1alpha1.DatabaseTLSModeDisable {
+			params = append(params, urlParameter{"sslrootcert", DatabaseCAFile})
+		}
+
+		return params
+	}
+}
+
+// GetDatabaseURLProperties returns KC_DB_URL_PROPERTIES composed of the TLS parameters and the extra parameters,
+// urlProperties is used as is.
+func GetDatabaseURLProperties(db *v1alpha1.Database) string {
+	if db.URLProperties != "" {
+		return db.URLProperties
+	}
+
+	var params []urlParameter
+	for _, p := range getDatabaseTLSParameters(db) {
+		if _, ok := db.Parameters[p.Name]; !ok {
+			params = append(params, p)
+		}
+	}
+
+	names := make([]string, 0, len(db.Parameters))
+	for name := range db.Parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		params = append(params, urlParameter{name, db.Parameters[name]})
+	}
+
+	if len(params) == 0 {
+		return ""
+	}
+
+	prefix, separator := "?", "&"
+	if db.GetVendor() == v1alpha1.DatabaseVendorMSSQL {
+		prefix, separator = ";", ";"
+	}
+
+	pairs := make([]string, 0, len(params))
+	for _, p := range params {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", p.Name, p.Value))
+	}
+
+	return prefix + strings.Join(pairs, separator)
+}
+
+func getDatabaseCAVolume(ca *v1alpha1.CertificateSource) v12.Volume {
+	volume := v12.Volume{
+		Name: DatabaseCAVolume,
+	}
+
+	if ca.Secret != nil {
+		volume.Secret = &v12.SecretVolumeSource{
+			SecretName: ca.Secret.Name,
+			Items: []v12.KeyToPath{
+				{Key: ca.Secret.Key, Path: DatabaseCAKey},
+			},
+			DefaultMode: &[]int32{420}[0],
+		}
+	} else {
+		volume.ConfigMap = &v12.ConfigMapVolumeSource{
+			LocalObjectReference: ca.ConfigMap.LocalObjectReference,
+			Items: []v12.KeyToPath{
+				{Key: ca.ConfigMap.Key, Path: DatabaseCAKey},
+			},
+			DefaultMode: &[]int32{420}[0],
+		}
+	}
+
+	return volume
+}
+
+// ValidateDatabaseCA checks the database CA holds PEM certificates, pods would otherwise fail on startup.
+// The CA is read without the manager cache, Secrets of users don't carry its watched label.
+func ValidateDatabaseCA(ctx context.Context, c client.Reader, cr *v1alpha1.Keycloak) error {
+	ca := getDatabaseCA(cr)
+	if ca == nil {
+		return nil
+	}
+
+	var data []byte
+	if ca.Secret != nil {
+		secret := &v12.Secret{}
+		err := c.Get(ctx, client.ObjectKey{
+			Name:      ca.Secret.Name,
+			Namespace: cr.Namespace,
+		}, secret)
+		if err != nil {
+			return fmt.Errorf("failed to get database CA secret %s: %w", ca.Secret.Name, err)
+		}
+
+		data = secret.Data[ca.Secret.Key]
+	} else {
+		cm := &v12.ConfigMap{}
+		err := c.Get(ctx, client.ObjectKey{
+			Name:      ca.ConfigMap.Name,
+			Namespace: cr.Namespace,
+		}, cm)
+		if err != nil {
+			return fmt.Errorf("failed to get database CA configmap %s: %w", ca.ConfigMap.Name, err)
+		}
+
+		data = []byte(cm.Data[ca.ConfigMap.Key])
+	}
+
+	count := 0
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		if _, err := x509.ParseCertificate(block.Bytes); err != nil {
+			return fmt.Errorf("invalid database CA certificate: %w", err)
+		}
+
+		count++
+	}
+
+	if count == 0 {
+		return fmt.Errorf("database CA contains no PEM certificate")
+	}
+
+	return nil
+}
@@ -0,0 +1,174 @@
+package rhbk
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+)
+
+type featureType int
+
+const (
+	featureDefault featureType = iota
+	featureDisabledByDefault
+	featurePreview
+	featureExperimental
+	featureDeprecated
+)
+
+// previewFeature enables every preview feature at once
+const previewFeature = "preview"
+
+var features26_0 = map[string]featureType{
+	"account":                     featureDefault,
+	"account-api":                 featureDefault,
+	"admin":                       featureDefault,
+	"admin-api":                   featureDefault,
+	"authorization":               featureDefault,
+	"ciba":                        featureDefault,
+	"client-policies":             featureDefault,
+	"device-flow":                 featureDefault,
+	"hostname":                    featureDefault,
+	"impersonation":               featureDefault,
+	"js-adapter":                  featureDefault,
+	"kerberos":                    featureDefault,
+	"login":                       featureDefault,
+	"organization":                featureDefault,
+	"par":                         featureDefault,
+	"persistent-user-sessions":    featureDefault,
+	"step-up-authentication":      featureDefault,
+	"web-authn":                   featureDefault,
+	"docker":                      featureDisabledByDefault,
+	"fips":                        featureDisabledByDefault,
+	"multi-site":                  featureDisabledByDefault,
+	"admin-fine-grained-authz":    featurePreview,
+	"client-secret-rotation":      featurePreview,
+	"dpop":                        featurePreview,
+	"recovery-codes":              featurePreview,
+	"scripts":                     featurePreview,
+	"token-exchange":              featurePreview,
+	"update-email":                featurePreview,
+	"cache-embedded-remote-store": featureExperimental,
+	"client-types":                featureExperimental,
+	"declarative-ui":              featureExperimental,
+	"dynamic-scopes":              featureExperimental,
+	"oid4vc-vci":                  featureExperimental,
+	"transient-users":             featureExperimental,
+	"instagram-broker":            featureDeprecated,
+	"linkedin-oauth":              featureDeprecated,
+}
+
+// Features of each RHBK minor version, new versions are added here before they are supported
+var featureCatalog = map[string]map[string]featureType{
+	"26.0": features26_0,
+	"26.2": withFeatures(features26_0, map[string]featureType{
+		"admin-fine-grained-authz": featureDefault,
+		"opentelemetry":            featureDefault,
+		"token-exchange-standard":  featureDefault,
+		"passkeys":                 featurePreview,
+		"rolling-updates":          featurePreview,
+		"quick-theme":              featureExperimental,
+	}),
+}
+
+func withFeatures(base map[string]featureType, changes map[string]featureType) map[string]featureType {
+	features := make(map[string]featureType, len(base)+len(changes))
+	for name, t := range base {
+		features[name] = t
+	}
+
+	for name, t := range changes {
+		features[name] = t
+	}
+
+	return features
+}
+
+// getFeatureName strips the version of a feature, e.g. token-exchange:v1
+func getFeatureName(feature string) string {
+	name, _, _ := strings.Cut(feature, ":")
+	return name
+}
+
+// getFeatureCatalog returns the features of the image version, nil if the version is unknown
+func getFeatureCatalog(image string) map[string]featureType {
+	version := GetVersion(image)
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return nil
+	}
+
+	minor, _, _ := strings.Cut(parts[1], "-")
+	return featureCatalog[fmt.Sprintf("%s.%s", parts[0], minor)]
+}
+
+// ValidateFeatures checks the configured features exist in the image version, unknown versions are not validated
+func ValidateFeatures(cr *v1alpha1.Keycloak) error {
+	if cr.Spec.Features == nil {
+		return nil
+	}
+
+	catalog := getFeatureCatalog(GetImage(cr))
+	if catalog == nil {
+		return nil
+	}
+
+	var configured []string
+	configured = append(configured, cr.Spec.Features.Enabled...)
+	configured = append(configured, cr.Spec.Features.Disabled...)
+
+	var unknown []string
+	for _, feature := range configured {
+		name := getFeatureName(feature)
+		if _, ok := catalog[name]; !ok && name != previewFeature {
+			unknown = append(unknown, feature)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown features for RHBK %s: %s", GetVersion(GetImage(cr)), strings.Join(unknown, ", "))
+	}
+
+	return nil
+}
+
+// GetEffectiveFeatures returns the sorted features enabled on the image, only the configured ones if the version is unknown
+func GetEffectiveFeatures(cr *v1alpha1.Keycloak) []string {
+	catalog := getFeatureCatalog(GetImage(cr))
+	enabled := map[string]string{}
+
+	for name, t := range catalog {
+		if t == featureDefault {
+			enabled[name] = name
+		}
+	}
+
+	if cr.Spec.Features != nil {
+		for _, feature := range cr.Spec.Features.Enabled {
+			if feature != previewFeature || catalog == nil {
+				enabled[getFeatureName(feature)] = feature
+				continue
+			}
+
+			for name, t := range catalog {
+				if t == featurePreview {
+					enabled[name] = name
+				}
+			}
+		}
+
+		for _, feature := range cr.Spec.Features.Disabled {
+			delete(enabled, getFeatureName(feature))
+		}
+	}
+
+	features := make([]string, 0, len(enabled))
+	for _, feature := range enabled {
+		features = append(features, feature)
+	}
+	sort.Strings(features)
+
+	return features
+}
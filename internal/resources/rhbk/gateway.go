@@ -0,0 +1,198 @@
+package rhbk
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources"
+)
+
+// Gateway API types are handled unstructured, clusters serve different versions of the API
+var (
+	GatewayGVK          = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "Gateway"}
+	HTTPRouteGVK        = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+	TLSRouteGVK         = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1alpha2", Kind: "TLSRoute"}
+	BackendTLSPolicyGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "BackendTLSPolicy"}
+)
+
+func GetGatewayRouteName(cr *v1alpha1.Keycloak) string {
+	return cr.Name
+}
+
+func NewUnstructured(gvk schema.GroupVersionKind, name string, namespace string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	obj.SetName(name)
+	obj.SetNamespace(namespace)
+	return obj
+}
+
+func getGatewaySpec(cr *v1alpha1.Keycloak) *v1alpha1.GatewaySpec {
+	if cr.Spec.NetworkConfig == nil || cr.Spec.NetworkConfig.Gateway == nil {
+		return &v1alpha1.GatewaySpec{}
+	}
+
+	return cr.Spec.NetworkConfig.Gateway
+}
+
+// GetGatewayHostname returns the configured hostname, or generates one from the wildcard hostname of the Gateway listener
+func GetGatewayHostname(ctx context.Context, c client.Client, cr *v1alpha1.Keycloak) (string, error) {
+	if hostname := cr.Spec.GetHostname(); hostname != "" {
+		return hostname, nil
+	}
+
+	parentRef := getGatewaySpec(cr).ParentRef
+	namespace := parentRef.Namespace
+	if namespace == "" {
+		namespace = cr.Namespace
+	}
+
+	gateway := NewUnstructured(GatewayGVK, parentRef.Name, namespace)
+	err := c.Get(ctx, client.ObjectKeyFromObject(gateway), gateway)
+	if err != nil {
+		return "", fmt.Errorf("failed to get gateway %s/%s: %w", namespace, parentRef.Name, err)
+	}
+
+	listeners, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "listeners")
+	for _, l := range listeners {
+		listener, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if parentRef.SectionName != "" && listener["name"] != parentRef.SectionName {
+			continue
+		}
+
+		hostname, _ := listener["hostname"].(string)
+		if domain, ok := strings.CutPrefix(hostname, "*."); ok {
+			return fmt.Sprintf("%s-%s.%s", cr.Name, cr.Namespace, domain), nil
+		} else if hostname != "" {
+			return hostname, nil
+		}
+	}
+
+	return "", fmt.Errorf("gateway %s/%s has no listener hostname, networkOptions.hostname is required", namespace, parentRef.Name)
+}
+
+// RHBKGatewayRoute attaches Keycloak to a Gateway, an HTTPRoute reencrypts and a TLSRoute passes TLS through
+type RHBKGatewayRoute struct {
+	Keycloak *v1alpha1.Keycloak
+	Scheme   *runtime.Scheme
+	Hostname string
+	Resource *unstructured.Unstructured
+}
+
+func (s *RHBKGatewayRoute) Build() error {
+	defaultLabels := map[string]string{}
+	resources.DecorateDefaultLabels(defaultLabels)
+
+	parentRef := getGatewaySpec(s.Keycloak).ParentRef
+	ref := map[string]interface{}{
+		"group": GatewayGVK.Group,
+		"kind":  GatewayGVK.Kind,
+		"name":  parentRef.Name,
+	}
+	if parentRef.Namespace != "" {
+		ref["namespace"] = parentRef.Namespace
+	}
+	if parentRef.SectionName != "" {
+		ref["sectionName"] = parentRef.SectionName
+	}
+
+	rule := map[string]interface{}{
+		"backendRefs": []interface{}{
+			map[string]interface{}{
+				"name": GetSvcName(s.Keycloak),
+				"port": int64(HttpsPort),
+			},
+		},
+	}
+
+	if s.Resource.GroupVersionKind() == HTTPRouteGVK {
+		rule["matches"] = []interface{}{
+			map[string]interface{}{
+				"path": map[string]interface{}{
+					"type":  "PathPrefix",
+					"value": "/",
+				},
+			},
+		}
+	}
+
+	s.Resource.SetLabels(defaultLabels)
+	s.Resource.Object["spec"] = map[string]interface{}{
+		"parentRefs": []interface{}{ref},
+		"hostnames":  []interface{}{s.Hostname},
+		"rules":      []interface{}{rule},
+	}
+
+	return controllerutil.SetControllerReference(s.Keycloak, s.Resource, s.Scheme)
+}
+
+func (s *RHBKGatewayRoute) CreateOrUpdate(ctx context.Context, c client.Client) error {
+	gvk := HTTPRouteGVK
+	if getGatewaySpec(s.Keycloak).GetTLSTermination() == v1alpha1.GatewayTLSPassthrough {
+		gvk = TLSRouteGVK
+	}
+
+	s.Resource = NewUnstructured(gvk, GetGatewayRouteName(s.Keycloak), s.Keycloak.Namespace)
+	_, err := controllerutil.CreateOrUpdate(ctx, c, s.Resource, s.Build)
+
+	return err
+}
+
+// RHBKBackendTLSPolicy makes the gateway reencrypt traffic to the Keycloak service and verify its certificate
+type RHBKBackendTLSPolicy struct {
+	Keycloak *v1alpha1.Keycloak
+	Scheme   *runtime.Scheme
+	Resource *unstructured.Unstructured
+}
+
+func (s *RHBKBackendTLSPolicy) Build() error {
+	defaultLabels := map[string]string{}
+	resources.DecorateDefaultLabels(defaultLabels)
+
+	ca := getGatewaySpec(s.Keycloak).BackendCACertificate
+	validation := map[string]interface{}{
+		// Matches the service serving certificate
+		"hostname": fmt.Sprintf("%s.%s.svc", GetSvcName(s.Keycloak), s.Keycloak.Namespace),
+		"caCertificateRefs": []interface{}{
+			map[string]interface{}{
+				"group": "",
+				"kind":  "ConfigMap",
+				"name":  ca.Name,
+			},
+		},
+	}
+
+	s.Resource.SetLabels(defaultLabels)
+	s.Resource.Object["spec"] = map[string]interface{}{
+		"targetRefs": []interface{}{
+			map[string]interface{}{
+				"group":       "",
+				"kind":        "Service",
+				"name":        GetSvcName(s.Keycloak),
+				"sectionName": ApplicationServicePortName,
+			},
+		},
+		"validation": validation,
+	}
+
+	return controllerutil.SetControllerReference(s.Keycloak, s.Resource, s.Scheme)
+}
+
+func (s *RHBKBackendTLSPolicy) CreateOrUpdate(ctx context.Context, c client.Client) error {
+	s.Resource = NewUnstructured(BackendTLSPolicyGVK, GetGatewayRouteName(s.Keycloak), s.Keycloak.Namespace)
+	_, err := controllerutil.CreateOrUpdate(ctx, c, s.Resource, s.Build)
+
+	return err
+}
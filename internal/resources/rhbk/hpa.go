@@ -0,0 +1,93 @@
+package rhbk
+
+import (
+	"context"
+
+	v2 "k8s.io/api/autoscaling/v2"
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/constants"
+	"github.com/stakater/rhbk-operator/internal/resources"
+)
+
+const DefaultTargetCPUUtilizationPercentage = 80
+
+type RHBKHorizontalPodAutoscaler struct {
+	Keycloak *v1alpha1.Keycloak
+	Scheme   *runtime.Scheme
+	Resource *v2.HorizontalPodAutoscaler
+}
+
+func GetHorizontalPodAutoscalerName(cr *v1alpha1.Keycloak) string {
+	return cr.Name
+}
+
+// GetPodSelector returns the label selector of the pods of the instance
+func GetPodSelector(cr *v1alpha1.Keycloak) string {
+	return labels.SelectorFromSet(map[string]string{
+		constants.RHBKInstanceLabel: cr.Name,
+	}).String()
+}
+
+func getAutoscalingMetrics(spec *v1alpha1.AutoscalingSpec) []v2.MetricSpec {
+	target := spec.TargetCPUUtilizationPercentage
+	if target == nil && len(spec.Metrics) == 0 {
+		target = &[]int32{DefaultTargetCPUUtilizationPercentage}[0]
+	}
+
+	var metrics []v2.MetricSpec
+	if target != nil {
+		metrics = append(metrics, v2.MetricSpec{
+			Type: v2.ResourceMetricSourceType,
+			Resource: &v2.ResourceMetricSource{
+				Name: v12.ResourceCPU,
+				Target: v2.MetricTarget{
+					Type:               v2.UtilizationMetricType,
+					AverageUtilization: target,
+				},
+			},
+		})
+	}
+
+	return append(metrics, spec.Metrics...)
+}
+
+func (s *RHBKHorizontalPodAutoscaler) Build() error {
+	defaultLabels := map[string]string{}
+	resources.DecorateDefaultLabels(defaultLabels)
+
+	spec := s.Keycloak.Spec.Autoscaling
+
+	s.Resource.Labels = defaultLabels
+	s.Resource.Spec = v2.HorizontalPodAutoscalerSpec{
+		ScaleTargetRef: v2.CrossVersionObjectReference{
+			APIVersion: "apps/v1",
+			Kind:       "StatefulSet",
+			Name:       GetStatefulSetName(s.Keycloak),
+		},
+		MinReplicas: &spec.MinReplicas,
+		MaxReplicas: spec.MaxReplicas,
+		Metrics:     getAutoscalingMetrics(spec),
+	}
+
+	return controllerutil.SetControllerReference(s.Keycloak, s.Resource, s.Scheme)
+}
+
+func (s *RHBKHorizontalPodAutoscaler) CreateOrUpdate(ctx context.Context, c client.Client) error {
+	s.Resource = &v2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetHorizontalPodAutoscalerName(s.Keycloak),
+			Namespace: s.Keycloak.Namespace,
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, c, s.Resource, s.Build)
+
+	return err
+}
@@ -0,0 +1,100 @@
+package rhbk
+
+import (
+	"context"
+
+	v1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources"
+)
+
+// Keycloak only serves HTTPS, ingress-nginx has to re-encrypt
+const IngressBackendProtocolAnnotation = "nginx.ingress.kubernetes.io/backend-protocol"
+
+type RHBKIngress struct {
+	Keycloak *v1alpha1.Keycloak
+	Scheme   *runtime.Scheme
+	Resource *v1.Ingress
+}
+
+func GetIngressName(cr *v1alpha1.Keycloak) string {
+	return cr.Name
+}
+
+func (s *RHBKIngress) Build() error {
+	defaultLabels := map[string]string{}
+	resources.DecorateDefaultLabels(defaultLabels)
+
+	spec := s.Keycloak.Spec.Ingress
+	if spec == nil {
+		spec = &v1alpha1.IngressSpec{}
+	}
+
+	annotations := map[string]string{
+		IngressBackendProtocolAnnotation: "HTTPS",
+	}
+	for k, v := range spec.Annotations {
+		annotations[k] = v
+	}
+
+	hostname := s.Keycloak.Spec.GetHostname()
+	pathType := v1.PathTypePrefix
+
+	s.Resource.Labels = defaultLabels
+	s.Resource.Annotations = annotations
+	s.Resource.Spec = v1.IngressSpec{
+		IngressClassName: spec.ClassName,
+		Rules: []v1.IngressRule{
+			{
+				Host: hostname,
+				IngressRuleValue: v1.IngressRuleValue{
+					HTTP: &v1.HTTPIngressRuleValue{
+						Paths: []v1.HTTPIngressPath{
+							{
+								Path:     "/",
+								PathType: &pathType,
+								Backend: v1.IngressBackend{
+									Service: &v1.IngressServiceBackend{
+										Name: GetSvcName(s.Keycloak),
+										Port: v1.ServiceBackendPort{
+											Name: ApplicationServicePortName,
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	if spec.TLSSecret != "" {
+		s.Resource.Spec.TLS = []v1.IngressTLS{
+			{
+				Hosts:      []string{hostname},
+				SecretName: spec.TLSSecret,
+			},
+		}
+	}
+
+	return controllerutil.SetControllerReference(s.Keycloak, s.Resource, s.Scheme)
+}
+
+func (s *RHBKIngress) CreateOrUpdate(ctx context.Context, c client.Client) error {
+	s.Resource = &v1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetIngressName(s.Keycloak),
+			Namespace: s.Keycloak.Namespace,
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, c, s.Resource, s.Build)
+
+	return err
+}
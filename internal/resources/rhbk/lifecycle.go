@@ -0,0 +1,113 @@
+package rhbk
+
+import (
+	"fmt"
+	"time"
+
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+)
+
+const (
+	// Time for routers and load balancers to stop sending requests to a terminating pod
+	DefaultShutdownDelay = 10 * time.Second
+	// Time for Keycloak to finish in-flight requests once the delay is over
+	DefaultShutdownTimeout = 30 * time.Second
+)
+
+func getLifecycle(cr *v1alpha1.Keycloak) *v1alpha1.LifecycleSpec {
+	if cr.Spec.Lifecycle == nil {
+		return &v1alpha1.LifecycleSpec{}
+	}
+
+	return cr.Spec.Lifecycle
+}
+
+func GetShutdownDelay(cr *v1alpha1.Keycloak) time.Duration {
+	if delay := getLifecycle(cr).ShutdownDelay; delay != nil {
+		return delay.Duration
+	}
+
+	return DefaultShutdownDelay
+}
+
+// GetTerminationGracePeriodSeconds returns the grace period of the pods, long enough for the shutdown delay
+// and the in-flight requests by default
+func GetTerminationGracePeriodSeconds(cr *v1alpha1.Keycloak) *int64 {
+	if period := getLifecycle(cr).TerminationGracePeriodSeconds; period != nil {
+		return period
+	}
+
+	seconds := int64((GetShutdownDelay(cr) + DefaultShutdownTimeout).Seconds())
+	return &seconds
+}
+
+// GetPreStopHook delays the shutdown of Keycloak until the pod is removed from the endpoints,
+// the readiness probe alone leaves requests routed to a stopped server
+func GetPreStopHook(cr *v1alpha1.Keycloak) *v12.Lifecycle {
+	delay := GetShutdownDelay(cr)
+	if delay <= 0 {
+		return nil
+	}
+
+	return &v12.Lifecycle{
+		PreStop: &v12.LifecycleHandler{
+			Exec: &v12.ExecAction{
+				Command: []string{"/bin/bash", "-c", fmt.Sprintf("sleep %d", int64(delay.Seconds()))},
+			},
+		},
+	}
+}
+
+// getProbe returns an HTTPS probe of the management port with the timings of the spec over the defaults
+func getProbe(path string, timings *v1alpha1.ProbeTimings, periodSeconds int32, failureThreshold int32) *v12.Probe {
+	probe := &v12.Probe{
+		ProbeHandler: v12.ProbeHandler{
+			HTTPGet: &v12.HTTPGetAction{
+				Path: path,
+				Port: intstr.IntOrString{
+					IntVal: ManagementPort,
+				},
+				Scheme: v12.URISchemeHTTPS,
+			},
+		},
+		PeriodSeconds:    periodSeconds,
+		FailureThreshold: failureThreshold,
+	}
+
+	if timings == nil {
+		return probe
+	}
+
+	if timings.InitialDelaySeconds != nil {
+		probe.InitialDelaySeconds = *timings.InitialDelaySeconds
+	}
+
+	if timings.PeriodSeconds != nil {
+		probe.PeriodSeconds = *timings.PeriodSeconds
+	}
+
+	if timings.TimeoutSeconds != nil {
+		probe.TimeoutSeconds = *timings.TimeoutSeconds
+	}
+
+	if timings.FailureThreshold != nil {
+		probe.FailureThreshold = *timings.FailureThreshold
+	}
+
+	return probe
+}
+
+func GetLivenessProbe(cr *v1alpha1.Keycloak) *v12.Probe {
+	return getProbe("/health/live", getLifecycle(cr).LivenessProbe, 10, 3)
+}
+
+func GetReadinessProbe(cr *v1alpha1.Keycloak) *v12.Probe {
+	return getProbe("/health/ready", getLifecycle(cr).ReadinessProbe, 10, 3)
+}
+
+func GetStartupProbe(cr *v1alpha1.Keycloak) *v12.Probe {
+	return getProbe("/health/started", getLifecycle(cr).StartupProbe, 1, 600)
+}
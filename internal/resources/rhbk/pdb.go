@@ -0,0 +1,66 @@
+package rhbk
+
+import (
+	"context"
+
+	v1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources"
+)
+
+type RHBKPodDisruptionBudget struct {
+	Keycloak *v1alpha1.Keycloak
+	Scheme   *runtime.Scheme
+	Resource *v1.PodDisruptionBudget
+}
+
+func GetPodDisruptionBudgetName(cr *v1alpha1.Keycloak) string {
+	return cr.Name
+}
+
+// IsPodDisruptionBudgetRequired tells whether the instance may run more than one pod, a budget would block draining a single pod
+func IsPodDisruptionBudgetRequired(cr *v1alpha1.Keycloak) bool {
+	return cr.Spec.GetMaxInstances() > 1
+}
+
+func GetMaxUnavailable(cr *v1alpha1.Keycloak) intstr.IntOrString {
+	if cr.Spec.DisruptionBudget == nil || cr.Spec.DisruptionBudget.MaxUnavailable == nil {
+		return intstr.FromInt32(1)
+	}
+
+	return *cr.Spec.DisruptionBudget.MaxUnavailable
+}
+
+func (s *RHBKPodDisruptionBudget) Build() error {
+	defaultLabels := map[string]string{}
+	resources.DecorateDefaultLabels(defaultLabels)
+
+	maxUnavailable := GetMaxUnavailable(s.Keycloak)
+
+	s.Resource.Labels = defaultLabels
+	s.Resource.Spec = v1.PodDisruptionBudgetSpec{
+		MaxUnavailable: &maxUnavailable,
+		Selector:       getInstanceSelector(s.Keycloak),
+	}
+
+	return controllerutil.SetControllerReference(s.Keycloak, s.Resource, s.Scheme)
+}
+
+func (s *RHBKPodDisruptionBudget) CreateOrUpdate(ctx context.Context, c client.Client) error {
+	s.Resource = &v1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetPodDisruptionBudgetName(s.Keycloak),
+			Namespace: s.Keycloak.Namespace,
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, c, s.Resource, s.Build)
+
+	return err
+}
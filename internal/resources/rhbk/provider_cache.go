@@ -0,0 +1,103 @@
+package rhbk
+
+import (
+	"context"
+	"fmt"
+
+	v12 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources"
+	"github.com/stakater/rhbk-operator/internal/resources/realm"
+)
+
+const DefaultProviderCacheSize = "1Gi"
+
+type RHBKProviderCache struct {
+	Keycloak *v1alpha1.Keycloak
+	Scheme   *runtime.Scheme
+	Resource *v12.PersistentVolumeClaim
+}
+
+func GetProviderCacheName(cr *v1alpha1.Keycloak) string {
+	return fmt.Sprintf("%s-provider-cache", cr.Name)
+}
+
+// IsProviderCacheManaged tells whether the operator creates the claim of the provider cache
+func IsProviderCacheManaged(cr *v1alpha1.Keycloak) bool {
+	return cr.Spec.ProviderCache != nil && cr.Spec.ProviderCache.ClaimName == ""
+}
+
+// GetProviderCacheClaimName returns the claim shared by the pods and the import jobs, empty without a cache
+func GetProviderCacheClaimName(cr *v1alpha1.Keycloak) string {
+	if cr.Spec.ProviderCache == nil {
+		return ""
+	}
+
+	if cr.Spec.ProviderCache.ClaimName != "" {
+		return cr.Spec.ProviderCache.ClaimName
+	}
+
+	return GetProviderCacheName(cr)
+}
+
+// GetProviderCacheVolumes returns the volume of the provider cache, only the fetch container mounts it
+func GetProviderCacheVolumes(cr *v1alpha1.Keycloak) []v12.Volume {
+	claim := GetProviderCacheClaimName(cr)
+	if claim == "" {
+		return nil
+	}
+
+	return []v12.Volume{
+		{
+			Name: realm.ProviderCacheVolume,
+			VolumeSource: v12.VolumeSource{
+				PersistentVolumeClaim: &v12.PersistentVolumeClaimVolumeSource{
+					ClaimName: claim,
+				},
+			},
+		},
+	}
+}
+
+func (s *RHBKProviderCache) Build() error {
+	defaultLabels := map[string]string{}
+	resources.DecorateDefaultLabels(defaultLabels)
+
+	size := resource.MustParse(DefaultProviderCacheSize)
+	if s.Keycloak.Spec.ProviderCache.Size != nil {
+		size = *s.Keycloak.Spec.ProviderCache.Size
+	}
+
+	s.Resource.Labels = defaultLabels
+
+	// Access modes and storage class are immutable, only the size of an existing claim can grow
+	if s.Resource.CreationTimestamp.IsZero() {
+		s.Resource.Spec.AccessModes = []v12.PersistentVolumeAccessMode{v12.ReadWriteMany}
+		s.Resource.Spec.StorageClassName = s.Keycloak.Spec.ProviderCache.StorageClassName
+	}
+
+	s.Resource.Spec.Resources.Requests = v12.ResourceList{
+		v12.ResourceStorage: size,
+	}
+
+	return controllerutil.SetControllerReference(s.Keycloak, s.Resource, s.Scheme)
+}
+
+func (s *RHBKProviderCache) CreateOrUpdate(ctx context.Context, c client.Client) error {
+	s.Resource = &v12.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetProviderCacheName(s.Keycloak),
+			Namespace: s.Keycloak.Namespace,
+		},
+	}
+
+	_, err := controllerutil.CreateOrUpdate(ctx, c, s.Resource, s.Build)
+
+	return err
+}
@@ -14,6 +14,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 )
 
+func GetRouteName(cr *v1alpha1.Keycloak) string {
+	return cr.Name
+}
+
 type RHBKRoute struct {
 	Keycloak *v1alpha1.Keycloak
 	Scheme   *runtime.Scheme
@@ -23,16 +27,10 @@ type RHBKRoute struct {
 func (s *RHBKRoute) Build() error {
 	defaultLabels := map[string]string{}
 	resources.DecorateDefaultLabels(defaultLabels)
-	var hostname string
-	if s.Keycloak.Spec.NetworkConfig == nil {
-		hostname = ""
-	} else {
-		hostname = s.Keycloak.Spec.NetworkConfig.Hostname
-	}
 
 	s.Resource.Labels = defaultLabels
 	s.Resource.Spec = v1.RouteSpec{
-		Host: hostname,
+		Host: s.Keycloak.Spec.GetHostname(),
 		To: v1.RouteTargetReference{
 			Kind: "Service",
 			Name: GetSvcName(s.Keycloak),
@@ -57,7 +55,7 @@ func (s *RHBKRoute) Build() error {
 func (s *RHBKRoute) CreateOrUpdate(ctx context.Context, c client.Client) error {
 	s.Resource = &v1.Route{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      s.Keycloak.Name,
+			Name:      GetRouteName(s.Keycloak),
 			Namespace: s.Keycloak.Namespace,
 		},
 	}
@@ -0,0 +1,87 @@
+package rhbk
+
+import (
+	v12 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/constants"
+)
+
+// GetPodLabels returns the labels of the Keycloak pods, the StatefulSet selector only uses the default labels
+func GetPodLabels(cr *v1alpha1.Keycloak, defaultLabels map[string]string) map[string]string {
+	podLabels := map[string]string{}
+	for k, v := range defaultLabels {
+		podLabels[k] = v
+	}
+
+	podLabels[constants.RHBKInstanceLabel] = cr.Name
+	return podLabels
+}
+
+func getInstanceSelector(cr *v1alpha1.Keycloak) *metav1.LabelSelector {
+	return &metav1.LabelSelector{
+		MatchLabels: map[string]string{
+			constants.RHBKInstanceLabel: cr.Name,
+		},
+	}
+}
+
+func getScheduling(cr *v1alpha1.Keycloak) *v1alpha1.SchedulingSpec {
+	if cr.Spec.Scheduling == nil {
+		return &v1alpha1.SchedulingSpec{}
+	}
+
+	return cr.Spec.Scheduling
+}
+
+// getAffinity prefers nodes not running another pod of the instance
+func getAffinity(cr *v1alpha1.Keycloak) *v12.Affinity {
+	if affinity := getScheduling(cr).Affinity; affinity != nil {
+		if affinity.NodeAffinity == nil && affinity.PodAffinity == nil && affinity.PodAntiAffinity == nil {
+			return nil
+		}
+
+		return affinity
+	}
+
+	return &v12.Affinity{
+		PodAntiAffinity: &v12.PodAntiAffinity{
+			PreferredDuringSchedulingIgnoredDuringExecution: []v12.WeightedPodAffinityTerm{
+				{
+					Weight: 100,
+					PodAffinityTerm: v12.PodAffinityTerm{
+						LabelSelector: getInstanceSelector(cr),
+						TopologyKey:   v12.LabelHostname,
+					},
+				},
+			},
+		},
+	}
+}
+
+// getTopologySpreadConstraints spreads the pods of the instance across zones on a best effort basis
+func getTopologySpreadConstraints(cr *v1alpha1.Keycloak) []v12.TopologySpreadConstraint {
+	if constraints := getScheduling(cr).TopologySpreadConstraints; constraints != nil {
+		return constraints
+	}
+
+	return []v12.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       v12.LabelTopologyZone,
+			WhenUnsatisfiable: v12.ScheduleAnyway,
+			LabelSelector:     getInstanceSelector(cr),
+		},
+	}
+}
+
+// DecorateScheduling places the pods according to spec.scheduling
+func DecorateScheduling(cr *v1alpha1.Keycloak, spec *v12.PodSpec) {
+	scheduling := getScheduling(cr)
+	spec.NodeSelector = scheduling.NodeSelector
+	spec.Tolerations = scheduling.Tolerations
+	spec.PriorityClassName = scheduling.PriorityClassName
+	spec.Affinity = getAffinity(cr)
+	spec.TopologySpreadConstraints = getTopologySpreadConstraints(cr)
+}
@@ -25,7 +25,15 @@ const ApplicationServicePortName = "https"
 const ManagementPort = 9000
 const HttpsPort = 8443
 
+// ServingCertAnnotation makes the OpenShift service CA sign the certificate of the service
+const ServingCertAnnotation = "service.beta.openshift.io/serving-cert-secret-name"
+
+// GetTLSSecretName returns the secret holding the certificate of the HTTPS endpoint
 func GetTLSSecretName(cr *v1alpha1.Keycloak) string {
+	if cr.Spec.TLS != nil && cr.Spec.TLS.SecretName != "" {
+		return cr.Spec.TLS.SecretName
+	}
+
 	return cr.Name + "-tls"
 }
 
@@ -38,8 +46,9 @@ func (s *RHBKService) Build() error {
 	resources.DecorateDefaultLabels(defaultLabels)
 
 	s.Resource.Labels = defaultLabels
-	s.Resource.Annotations = map[string]string{
-		"service.beta.openshift.io/serving-cert-secret-name": GetTLSSecretName(s.Keycloak),
+	s.Resource.Annotations = map[string]string{}
+	if s.Keycloak.Spec.TLS == nil {
+		s.Resource.Annotations[ServingCertAnnotation] = GetTLSSecretName(s.Keycloak)
 	}
 
 	s.Resource.Spec = v1.ServiceSpec{
@@ -3,14 +3,15 @@ package rhbk
 import (
 	"context"
 	"fmt"
+	"os"
 	"strconv"
+	"strings"
 
 	v1 "k8s.io/api/apps/v1"
 	v12 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/apimachinery/pkg/util/intstr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
@@ -21,19 +22,65 @@ import (
 )
 
 const RHBKImage = "registry.redhat.io/rhbk/keycloak-rhel9:26.0-6"
+const RHBKImageEnv = "RELATED_IMAGE_RHBK"
+const DefaultDatabasePoolSize = 30
 
 type RHBKStatefulSet struct {
 	Keycloak *v1alpha1.Keycloak
 	HostName string
 	Scheme   *runtime.Scheme
 	Resource *v1.StatefulSet
-	Sizing   []*v1alpha1.RealmSizing
+	// Sizing of the imports targeting the instance, nil for imports declaring none
+	Sizing []*v1alpha1.RealmSizing
+	// Overrides Spec.Instances when set
+	Replicas *int32
+	// Hash of the theme ConfigMaps, see GetThemesHash
+	ThemesHash string
 }
 
 func GetStatefulSetName(cr *v1alpha1.Keycloak) string {
 	return cr.Name
 }
 
+// GetImage returns the RHBK image for the instance. The spec takes precedence
+// over the RELATED_IMAGE_RHBK operator env which is used for disconnected mirrors.
+func GetImage(cr *v1alpha1.Keycloak) string {
+	if cr.Spec.Image != "" {
+		return cr.Spec.Image
+	}
+
+	if image := os.Getenv(RHBKImageEnv); image != "" {
+		return image
+	}
+
+	return RHBKImage
+}
+
+// GetVersion returns the tag of an image reference, empty if the image is only pinned by digest
+func GetVersion(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+
+	return image[i+1:]
+}
+
+// GetContainerImage returns the RHBK image of the StatefulSet pod template
+func GetContainerImage(sts *v1.StatefulSet) string {
+	for _, c := range sts.Spec.Template.Spec.Containers {
+		if c.Name == constants.RHBKContainerName {
+			return c.Image
+		}
+	}
+
+	return ""
+}
+
 func getENV(name string, selector v1alpha1.SecretOption) v12.EnvVar {
 	env := v12.EnvVar{
 		Name: name,
@@ -50,28 +97,111 @@ func getENV(name string, selector v1alpha1.SecretOption) v12.EnvVar {
 	return env
 }
 
+// GetDatabasePool returns the pool settings of each pod with all sizes set. Explicit settings win over
+// the size derived from sizing, initial and min size default to the max size as connections are costly to open.
+// A derived max size is raised to explicit initial and min sizes, Agroal refuses a pool smaller than them.
+func (ks *RHBKStatefulSet) GetDatabasePool() v1alpha1.DatabasePool {
+	pool := v1alpha1.DatabasePool{}
+	if ks.Keycloak.Spec.Database != nil && ks.Keycloak.Spec.Database.Pool != nil {
+		pool = *ks.Keycloak.Spec.Database.Pool.DeepCopy()
+	}
+
+	if pool.MaxSize == nil {
+		size := int32(DefaultDatabasePoolSize)
+		if sizing := ks.GetSizing(); sizing != nil {
+			size = sizing.DatabasePoolSize(ks.Keycloak.Spec.GetMinInstances())
+		}
+
+		for _, explicit := range []*int32{pool.MinSize, pool.InitialSize} {
+			if explicit != nil && *explicit > size {
+				size = *explicit
+			}
+		}
+
+		pool.MaxSize = &size
+	}
+
+	if pool.MinSize == nil {
+		pool.MinSize = pool.MaxSize
+	}
+
+	if pool.InitialSize == nil {
+		pool.InitialSize = pool.MinSize
+	}
+
+	return pool
+}
+
 func (ks *RHBKStatefulSet) DecorateENV(vars []v12.EnvVar) []v12.EnvVar {
-	if ks.Keycloak.Spec.Database != nil {
+	if db := ks.Keycloak.Spec.Database; db != nil {
 		vars = append(vars, []v12.EnvVar{
 			{
 				Name:  "KC_DB",
-				Value: "postgres",
+				Value: string(db.GetVendor()),
 			},
-			getENV("KC_DB_USERNAME", ks.Keycloak.Spec.Database.User),
-			getENV("KC_DB_PASSWORD", ks.Keycloak.Spec.Database.Password),
-			getENV("KC_DB_URL_HOST", ks.Keycloak.Spec.Database.Host),
-			getENV("KC_DB_URL_PORT", ks.Keycloak.Spec.Database.Port),
+			getENV("KC_DB_USERNAME", db.User),
+			getENV("KC_DB_PASSWORD", db.Password),
+		}...)
+
+		if db.URL != nil {
+			vars = append(vars, getENV("KC_DB_URL", *db.URL))
+		} else {
+			vars = append(vars, getENV("KC_DB_URL_HOST", db.Host))
+
+			if db.Port.IsSet() {
+				vars = append(vars, getENV("KC_DB_URL_PORT", db.Port))
+			}
+
+			if db.Database != nil {
+				vars = append(vars, getENV("KC_DB_URL_DATABASE", *db.Database))
+			}
+
+			if properties := GetDatabaseURLProperties(db); properties != "" {
+				vars = append(vars, v12.EnvVar{
+					Name:  "KC_DB_URL_PROPERTIES",
+					Value: properties,
+				})
+			}
+		}
+
+		if db.Schema != "" {
+			vars = append(vars, v12.EnvVar{
+				Name:  "KC_DB_SCHEMA",
+				Value: db.Schema,
+			})
+		}
+
+		pool := ks.GetDatabasePool()
+		vars = append(vars, []v12.EnvVar{
 			{
 				Name:  "KC_DB_POOL_INITIAL_SIZE",
-				Value: "30",
+				Value: strconv.Itoa(int(*pool.InitialSize)),
 			},
 			{
 				Name:  "KC_DB_POOL_MIN_SIZE",
-				Value: "30",
+				Value: strconv.Itoa(int(*pool.MinSize)),
 			},
 			{
 				Name:  "KC_DB_POOL_MAX_SIZE",
-				Value: "30",
+				Value: strconv.Itoa(int(*pool.MaxSize)),
+			},
+		}...)
+	}
+
+	if sizing := ks.GetSizing(); sizing != nil {
+		instances := ks.Keycloak.Spec.GetMinInstances()
+		vars = append(vars, []v12.EnvVar{
+			{
+				Name:  "JAVA_OPTS_KC_HEAP",
+				Value: fmt.Sprintf("-XX:MaxRAMPercentage=%d -XX:MinRAMPercentage=%d -XX:InitialRAMPercentage=50", v1alpha1.HeapRAMPercentage, v1alpha1.HeapRAMPercentage),
+			},
+			{
+				Name:  "KC_CACHE_EMBEDDED_SESSIONS_MAX_COUNT",
+				Value: strconv.Itoa(int(sizing.CachedSessionsPerInstance(instances))),
+			},
+			{
+				Name:  "KC_CACHE_EMBEDDED_OFFLINE_SESSIONS_MAX_COUNT",
+				Value: strconv.Itoa(int(sizing.CachedOfflineSessionsPerInstance(instances))),
 			},
 		}...)
 	}
@@ -104,6 +234,29 @@ func (ks *RHBKStatefulSet) DecorateENV(vars []v12.EnvVar) []v12.EnvVar {
 		}...)
 	}
 
+	if ks.Keycloak.Spec.TLS != nil {
+		vars = append(vars, v12.EnvVar{
+			Name:  "KC_HTTPS_CERTIFICATES_RELOAD_PERIOD",
+			Value: GetCertificateReloadPeriod(ks.Keycloak),
+		})
+	}
+
+	if features := ks.Keycloak.Spec.Features; features != nil {
+		if len(features.Enabled) > 0 {
+			vars = append(vars, v12.EnvVar{
+				Name:  "KC_FEATURES",
+				Value: strings.Join(features.Enabled, ","),
+			})
+		}
+
+		if len(features.Disabled) > 0 {
+			vars = append(vars, v12.EnvVar{
+				Name:  "KC_FEATURES_DISABLED",
+				Value: strings.Join(features.Disabled, ","),
+			})
+		}
+	}
+
 	if len(ks.Keycloak.Spec.AdditionalOptions) > 0 {
 		for _, env := range ks.Keycloak.Spec.AdditionalOptions {
 			replacement := v12.EnvVar{
@@ -116,6 +269,10 @@ func (ks *RHBKStatefulSet) DecorateENV(vars []v12.EnvVar) []v12.EnvVar {
 				replacement.ValueFrom = &v12.EnvVarSource{
 					SecretKeyRef: env.Secret,
 				}
+			} else if env.ConfigMap != nil {
+				replacement.ValueFrom = &v12.EnvVarSource{
+					ConfigMapKeyRef: env.ConfigMap,
+				}
 			}
 
 			vars = resources.AddOrReplaceEnv(replacement, vars)
@@ -125,6 +282,15 @@ func (ks *RHBKStatefulSet) DecorateENV(vars []v12.EnvVar) []v12.EnvVar {
 	return vars
 }
 
+func (ks *RHBKStatefulSet) getTruststorePaths() string {
+	paths := "conf/truststores,/var/run/secrets/kubernetes.io/serviceaccount/ca.crt,/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt"
+	if getDatabaseCA(ks.Keycloak) != nil {
+		paths = fmt.Sprintf("%s,%s", paths, DatabaseCAFile)
+	}
+
+	return paths
+}
+
 func (ks *RHBKStatefulSet) DecorateVolume(vl []v12.Volume) []v12.Volume {
 	if ks.Keycloak.Spec.TrustedCABundles != nil {
 		vl = append(vl, v12.Volume{
@@ -146,15 +312,77 @@ func (ks *RHBKStatefulSet) DecorateVolume(vl []v12.Volume) []v12.Volume {
 		})
 	}
 
-	return vl
+	if ca := getDatabaseCA(ks.Keycloak); ca != nil {
+		vl = append(vl, getDatabaseCAVolume(ca))
+	}
+
+	vl = append(vl, GetThemeVolumes(ks.Keycloak)...)
+	vl = append(vl, GetProviderCacheVolumes(ks.Keycloak)...)
+	return append(vl, ks.Keycloak.Spec.Volumes...)
 }
 
 func (ks *RHBKStatefulSet) DecorateVolumeMounts(mounts []v12.VolumeMount) []v12.VolumeMount {
-	return mounts
+	if getDatabaseCA(ks.Keycloak) != nil {
+		mounts = append(mounts, v12.VolumeMount{
+			Name:      DatabaseCAVolume,
+			MountPath: DatabaseCAPath,
+			ReadOnly:  true,
+		})
+	}
+
+	mounts = append(mounts, GetThemeVolumeMounts(ks.Keycloak)...)
+	return append(mounts, ks.Keycloak.Spec.VolumeMounts...)
+}
+
+// decorateAnnotations keeps the annotations of the existing pods, e.g. of imports, and sets the themes hash
+func (ks *RHBKStatefulSet) decorateAnnotations(annotations map[string]string) map[string]string {
+	if ks.ThemesHash == "" {
+		delete(annotations, ThemesHashAnnotation)
+		return annotations
+	}
+
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	annotations[ThemesHashAnnotation] = ks.ThemesHash
+	return annotations
+}
+
+// GetSizing returns the sizing of the instance plus the sizing of its imports, nil when nothing is sized
+func (ks *RHBKStatefulSet) GetSizing() *v1alpha1.RealmSizing {
+	var sizings []*v1alpha1.RealmSizing
+	if ks.Keycloak.Spec.Sizing != nil {
+		sizings = append(sizings, ks.Keycloak.Spec.Sizing)
+	}
+
+	defaultSizing := ks.Keycloak.Spec.DefaultRealmSizing
+	for _, sizing := range ks.Sizing {
+		if sizing != nil || defaultSizing != nil {
+			sizings = append(sizings, sizing)
+		}
+	}
+
+	if len(sizings) == 0 {
+		return nil
+	}
+
+	sum := v1alpha1.SumSizing(sizings, defaultSizing)
+	if ks.Keycloak.Spec.Sizing != nil {
+		sum.DisableCPULimits = ks.Keycloak.Spec.Sizing.DisableCPULimits
+	}
+
+	return &sum
+}
+
+// GetResources returns the resources of each pod, spec.resources merged over the sizing result
+func (ks *RHBKStatefulSet) GetResources() v12.ResourceRequirements {
+	return resources.MergeResourceRequirements(ks.decorateSizing(), ks.Keycloak.Spec.Resources)
 }
 
 func (ks *RHBKStatefulSet) decorateSizing() v12.ResourceRequirements {
-	if ks.Keycloak.Spec.Sizing == nil {
+	sizing := ks.GetSizing()
+	if sizing == nil {
 		return v12.ResourceRequirements{
 			Requests: v12.ResourceList{
 				v12.ResourceMemory: resource.MustParse("1700Mi"),
@@ -165,16 +393,29 @@ func (ks *RHBKStatefulSet) decorateSizing() v12.ResourceRequirements {
 		}
 	}
 
-	return ks.Keycloak.Spec.Sizing.CalculateResourceLimits(ks.Keycloak.Spec.Instances)
+	return sizing.CalculateResourceLimits(ks.Keycloak.Spec.GetMinInstances())
 }
 
 func (ks *RHBKStatefulSet) Build() error {
 	defaultLabels := map[string]string{}
 	resources.DecorateDefaultLabels(defaultLabels)
 
+	replicas := ks.Keycloak.Spec.Instances
+	if autoscaling := ks.Keycloak.Spec.Autoscaling; autoscaling != nil {
+		// The autoscaler owns replicas, pods start from the minimum
+		replicas = ks.Resource.Spec.Replicas
+		if replicas == nil || *replicas == 0 {
+			replicas = &autoscaling.MinReplicas
+		}
+	}
+
+	if ks.Replicas != nil {
+		replicas = ks.Replicas
+	}
+
 	ks.Resource.Labels = defaultLabels
 	ks.Resource.Spec = v1.StatefulSetSpec{
-		Replicas: ks.Keycloak.Spec.Instances,
+		Replicas: replicas,
 		Selector: &metav1.LabelSelector{
 			MatchLabels: defaultLabels,
 		},
@@ -192,15 +433,16 @@ func (ks *RHBKStatefulSet) Build() error {
 		},
 		Template: v12.PodTemplateSpec{
 			ObjectMeta: metav1.ObjectMeta{
-				Labels:      defaultLabels,
-				Annotations: ks.Resource.Spec.Template.Annotations,
+				Labels:      GetPodLabels(ks.Keycloak, defaultLabels),
+				Annotations: ks.decorateAnnotations(ks.Resource.Spec.Template.Annotations),
 			},
 			Spec: v12.PodSpec{
-				InitContainers: realm.GetInitContainer(ks.Keycloak),
+				TerminationGracePeriodSeconds: GetTerminationGracePeriodSeconds(ks.Keycloak),
+				InitContainers:                append(realm.GetInitContainer(ks.Keycloak), GetThemeInitContainers(ks.Keycloak)...),
 				Containers: []v12.Container{
 					{
 						Name:            constants.RHBKContainerName,
-						Image:           RHBKImage,
+						Image:           GetImage(ks.Keycloak),
 						ImagePullPolicy: v12.PullAlways,
 						Args: []string{
 							fmt.Sprintf("-Djgroups.dns.query=%s.%s", GetDiscoverySvcName(ks.Keycloak), ks.Keycloak.Namespace),
@@ -248,7 +490,7 @@ func (ks *RHBKStatefulSet) Build() error {
 							getENV("KC_BOOTSTRAP_ADMIN_PASSWORD", ks.Keycloak.Spec.Admin.Password),
 							{
 								Name:  "KC_TRUSTSTORE_PATHS",
-								Value: "conf/truststores,/var/run/secrets/kubernetes.io/serviceaccount/ca.crt,/var/run/secrets/kubernetes.io/serviceaccount/service-ca.crt",
+								Value: ks.getTruststorePaths(),
 							},
 							{
 								Name:  "KC_TRACING_SERVICE_NAME",
@@ -271,46 +513,12 @@ func (ks *RHBKStatefulSet) Build() error {
 								Value: "/mnt/certificates/tls.key",
 							},
 						}),
-						Resources: ks.decorateSizing(),
-						LivenessProbe: &v12.Probe{
-							ProbeHandler: v12.ProbeHandler{
-								HTTPGet: &v12.HTTPGetAction{
-									Path: "/health/live",
-									Port: intstr.IntOrString{
-										IntVal: ManagementPort,
-									},
-									Scheme: v12.URISchemeHTTPS,
-								},
-							},
-							PeriodSeconds:    10,
-							FailureThreshold: 3,
-						},
-						ReadinessProbe: &v12.Probe{
-							ProbeHandler: v12.ProbeHandler{
-								HTTPGet: &v12.HTTPGetAction{
-									Path: "/health/ready",
-									Port: intstr.IntOrString{
-										IntVal: ManagementPort,
-									},
-									Scheme: v12.URISchemeHTTPS,
-								},
-							},
-							PeriodSeconds:    10,
-							FailureThreshold: 3,
-						},
-						StartupProbe: &v12.Probe{
-							ProbeHandler: v12.ProbeHandler{
-								HTTPGet: &v12.HTTPGetAction{
-									Path: "/health/started",
-									Port: intstr.IntOrString{
-										IntVal: ManagementPort,
-									},
-									Scheme: v12.URISchemeHTTPS,
-								},
-							},
-							PeriodSeconds:    1,
-							FailureThreshold: 600,
-						},
+						EnvFrom:        ks.Keycloak.Spec.EnvFrom,
+						Resources:      ks.GetResources(),
+						LivenessProbe:  GetLivenessProbe(ks.Keycloak),
+						ReadinessProbe: GetReadinessProbe(ks.Keycloak),
+						StartupProbe:   GetStartupProbe(ks.Keycloak),
+						Lifecycle:      GetPreStopHook(ks.Keycloak),
 						VolumeMounts: ks.DecorateVolumeMounts(
 							[]v12.VolumeMount{
 								{
@@ -352,7 +560,14 @@ func (ks *RHBKStatefulSet) Build() error {
 		},
 	}
 
-	err := controllerutil.SetControllerReference(ks.Keycloak, ks.Resource, ks.Scheme)
+	DecorateScheduling(ks.Keycloak, &ks.Resource.Spec.Template.Spec)
+
+	err := resources.MergePodTemplate(&ks.Resource.Spec.Template, ks.Keycloak.Spec.GetPodTemplate())
+	if err != nil {
+		return err
+	}
+
+	err = controllerutil.SetControllerReference(ks.Keycloak, ks.Resource, ks.Scheme)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,156 @@
+package rhbk
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	v12 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources"
+	"github.com/stakater/rhbk-operator/internal/resources/realm"
+)
+
+const ThemesPATH = "/opt/keycloak/themes"
+const ThemesVolume = "themes"
+
+// ThemesHashAnnotation rolls the pods when the content of a theme ConfigMap changes
+const ThemesHashAnnotation = "sso.stakater.com/themes-hash"
+
+func getThemeVolumeName(theme v1alpha1.Theme) string {
+	return realm.GetContainerName("theme", theme.Name)
+}
+
+// GetThemeVolumes returns the directory themes are copied to and the ConfigMaps of themes
+func GetThemeVolumes(cr *v1alpha1.Keycloak) []v12.Volume {
+	if len(cr.Spec.Themes) == 0 {
+		return nil
+	}
+
+	volumes := []v12.Volume{
+		{
+			Name: ThemesVolume,
+			VolumeSource: v12.VolumeSource{
+				EmptyDir: &v12.EmptyDirVolumeSource{},
+			},
+		},
+	}
+
+	for _, theme := range cr.Spec.Themes {
+		if theme.ConfigMap == nil {
+			continue
+		}
+
+		volumes = append(volumes, v12.Volume{
+			Name: getThemeVolumeName(theme),
+			VolumeSource: v12.VolumeSource{
+				ConfigMap: theme.ConfigMap,
+			},
+		})
+	}
+
+	return volumes
+}
+
+// GetThemeVolumeMounts mounts ConfigMap themes inside the themes directory
+func GetThemeVolumeMounts(cr *v1alpha1.Keycloak) []v12.VolumeMount {
+	if len(cr.Spec.Themes) == 0 {
+		return nil
+	}
+
+	mounts := []v12.VolumeMount{
+		{
+			Name:      ThemesVolume,
+			MountPath: ThemesPATH,
+		},
+	}
+
+	for _, theme := range cr.Spec.Themes {
+		if theme.ConfigMap == nil {
+			continue
+		}
+
+		mounts = append(mounts, v12.VolumeMount{
+			Name:      getThemeVolumeName(theme),
+			MountPath: path.Join(ThemesPATH, theme.Name),
+			ReadOnly:  true,
+		})
+	}
+
+	return mounts
+}
+
+// GetThemeInitContainers copies image themes into the themes directory
+func GetThemeInitContainers(cr *v1alpha1.Keycloak) []v12.Container {
+	var containers []v12.Container
+	for _, theme := range cr.Spec.Themes {
+		if theme.Image == nil {
+			continue
+		}
+
+		mount := v12.VolumeMount{Name: ThemesVolume, MountPath: ThemesPATH}
+		containers = append(containers, realm.NewImageCopyContainer(
+			realm.GetContainerName("theme", theme.Name), theme.Image, mount, path.Join(ThemesPATH, theme.Name)))
+	}
+
+	return containers
+}
+
+// GetThemesHash returns a hash of the ConfigMaps of themes, empty without any. ConfigMaps are read without
+// the manager cache, which only holds those labelled with sso.stakater.com/watched=true.
+func GetThemesHash(ctx context.Context, c client.Reader, cr *v1alpha1.Keycloak) (string, error) {
+	var content []string
+	for _, theme := range cr.Spec.Themes {
+		if theme.ConfigMap == nil {
+			continue
+		}
+
+		cm := &v12.ConfigMap{}
+		err := c.Get(ctx, client.ObjectKey{
+			Name:      theme.ConfigMap.Name,
+			Namespace: cr.Namespace,
+		}, cm)
+		if err != nil {
+			return "", fmt.Errorf("failed to get theme configmap %s: %w", theme.ConfigMap.Name, err)
+		}
+
+		for key, value := range cm.Data {
+			content = append(content, fmt.Sprintf("%s/%s=%s", theme.Name, key, value))
+		}
+
+		for key, value := range cm.BinaryData {
+			content = append(content, fmt.Sprintf("%s/%s=%x", theme.Name, key, value))
+		}
+	}
+
+	if len(content) == 0 {
+		return "", nil
+	}
+
+	sort.Strings(content)
+	hash, err := resources.GetHash(strings.Join(content, "\n"))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d", hash), nil
+}
+
+// IsThemeConfigMap reports whether a theme of the instance is mounted from the ConfigMap
+func IsThemeConfigMap(cr *v1alpha1.Keycloak, cm client.Object) bool {
+	if cr.Namespace != cm.GetNamespace() {
+		return false
+	}
+
+	for _, theme := range cr.Spec.Themes {
+		if theme.ConfigMap != nil && theme.ConfigMap.Name == cm.GetName() {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,98 @@
+package rhbk
+
+import (
+	"fmt"
+
+	v1 "k8s.io/api/apps/v1"
+	v12 "k8s.io/api/batch/v1"
+	v13 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/stakater/rhbk-operator/api/v1alpha1"
+	"github.com/stakater/rhbk-operator/internal/resources/realm"
+)
+
+const UpdateCompatibilityVolume = "update-compatibility"
+const UpdateCompatibilityPath = "/mnt/update-compatibility"
+const UpdateTargetImageAnnotation = "sso.stakater.com/target-image"
+
+// UpdateIncompatibleExitCode is returned by kc.sh update-compatibility check when a rolling update is not possible
+const UpdateIncompatibleExitCode = 3
+
+func GetUpdateCompatibilityJobName(cr *v1alpha1.Keycloak) string {
+	return cr.Name + "-update-compatibility"
+}
+
+// BuildUpdateCompatibilityJob builds a job checking whether pods of the running StatefulSet can be rolled to image.
+// Metadata of the running version is exported by an init container and verified by the target image,
+// the job completes when a rolling update is possible and fails by its pod failure policy when the instance has
+// to be recreated. Any other failure, e.g. an image without update-compatibility, fails the job by its backoff limit.
+func BuildUpdateCompatibilityJob(cr *v1alpha1.Keycloak, sts *v1.StatefulSet, image string, scheme *runtime.Scheme) (*v12.Job, error) {
+	// Keep the job out of the service selectors
+	jobLabels := map[string]string{
+		"app": "rhbk-update-compatibility",
+	}
+
+	template := realm.NewJobTemplate(sts, jobLabels)
+	kcContainer := &template.Spec.Containers[0]
+	metadataFile := fmt.Sprintf("%s/metadata.json", UpdateCompatibilityPath)
+
+	template.Spec.Volumes = append(template.Spec.Volumes, v13.Volume{
+		Name: UpdateCompatibilityVolume,
+		VolumeSource: v13.VolumeSource{
+			EmptyDir: &v13.EmptyDirVolumeSource{},
+		},
+	})
+
+	kcContainer.VolumeMounts = append(kcContainer.VolumeMounts, v13.VolumeMount{
+		Name:      UpdateCompatibilityVolume,
+		MountPath: UpdateCompatibilityPath,
+	})
+
+	metadata := kcContainer.DeepCopy()
+	metadata.Name = "metadata"
+	metadata.Command = []string{"/opt/keycloak/bin/kc.sh"}
+	metadata.Args = []string{"update-compatibility", "metadata", fmt.Sprintf("--file=%s", metadataFile)}
+	template.Spec.InitContainers = append(template.Spec.InitContainers, *metadata)
+
+	kcContainer.Image = image
+	kcContainer.Command = []string{"/opt/keycloak/bin/kc.sh"}
+	kcContainer.Args = []string{"update-compatibility", "check", fmt.Sprintf("--file=%s", metadataFile)}
+	checkContainer := kcContainer.Name
+
+	job := &v12.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      GetUpdateCompatibilityJobName(cr),
+			Namespace: cr.Namespace,
+			Labels:    jobLabels,
+			Annotations: map[string]string{
+				UpdateTargetImageAnnotation: image,
+			},
+		},
+		Spec: v12.JobSpec{
+			Template:     *template,
+			BackoffLimit: &[]int32{0}[0],
+			PodFailurePolicy: &v12.PodFailurePolicy{
+				Rules: []v12.PodFailurePolicyRule{
+					{
+						Action: v12.PodFailurePolicyActionFailJob,
+						OnExitCodes: &v12.PodFailurePolicyOnExitCodesRequirement{
+							ContainerName: &checkContainer,
+							Operator:      v12.PodFailurePolicyOnExitCodesOpIn,
+							Values:        []int32{UpdateIncompatibleExitCode},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	err := controllerutil.SetControllerReference(cr, job, scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
@@ -10,6 +10,7 @@ import (
 	v1 "k8s.io/api/batch/v1"
 	v13 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
 
 	"github.com/stakater/rhbk-operator/internal/constants"
 )
@@ -41,6 +42,28 @@ func IsJobCompleted(job *v1.Job) bool {
 	return false
 }
 
+func IsJobFailed(job *v1.Job) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == v1.JobFailed {
+			return condition.Status == v13.ConditionTrue
+		}
+	}
+
+	return false
+}
+
+// GetJobFailure returns the condition of a failed job, nil while the job has not failed
+func GetJobFailure(job *v1.Job) *v1.JobCondition {
+	for i := range job.Status.Conditions {
+		condition := &job.Status.Conditions[i]
+		if condition.Type == v1.JobFailed && condition.Status == v13.ConditionTrue {
+			return condition
+		}
+	}
+
+	return nil
+}
+
 func IsStatefulSetReady(sts *v12.StatefulSet) bool {
 	if sts == nil {
 		return false
@@ -49,6 +72,15 @@ func IsStatefulSetReady(sts *v12.StatefulSet) bool {
 	return sts.Status.ReadyReplicas == sts.Status.Replicas
 }
 
+// IsStatefulSetRolledOut checks that the latest spec has been observed and all pods run the update revision
+func IsStatefulSetRolledOut(sts *v12.StatefulSet) bool {
+	if sts == nil {
+		return false
+	}
+
+	return sts.Status.ObservedGeneration >= sts.Generation && sts.Status.CurrentRevision == sts.Status.UpdateRevision
+}
+
 func MatchSet(set1 map[string]string, set2 map[string]string) bool {
 	selector := labels.SelectorFromSet(set2)
 	return selector.Matches(labels.Set(set1))
@@ -87,6 +119,103 @@ func AddOrReplaceEnv(env v13.EnvVar, vars []v13.EnvVar) []v13.EnvVar {
 	return vars
 }
 
+// MergeResourceRequirements sets the requests and limits of override on top of base. A limit of base lower
+// than an overridden request is raised to the request, the API server rejects requests above limits.
+func MergeResourceRequirements(base v13.ResourceRequirements, override *v13.ResourceRequirements) v13.ResourceRequirements {
+	merged := *base.DeepCopy()
+	if override == nil {
+		return merged
+	}
+
+	if merged.Requests == nil && len(override.Requests) > 0 {
+		merged.Requests = v13.ResourceList{}
+	}
+
+	if merged.Limits == nil && len(override.Limits) > 0 {
+		merged.Limits = v13.ResourceList{}
+	}
+
+	for name, quantity := range override.Requests {
+		merged.Requests[name] = quantity.DeepCopy()
+	}
+
+	for name, quantity := range override.Limits {
+		merged.Limits[name] = quantity.DeepCopy()
+	}
+
+	for name, request := range override.Requests {
+		if _, ok := override.Limits[name]; ok {
+			continue
+		}
+
+		if limit, ok := merged.Limits[name]; ok && limit.Cmp(request) < 0 {
+			merged.Limits[name] = request.DeepCopy()
+		}
+	}
+
+	return merged
+}
+
+// MergePodTemplate applies overlay onto template by strategic merge patch. Unset fields of the overlay
+// are dropped before merging, null would remove them from the template.
+func MergePodTemplate(template *v13.PodTemplateSpec, overlay *v13.PodTemplateSpec) error {
+	if overlay == nil {
+		return nil
+	}
+
+	original, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(overlay)
+	if err != nil {
+		return err
+	}
+
+	var fields map[string]interface{}
+	if err = json.Unmarshal(patch, &fields); err != nil {
+		return err
+	}
+
+	patch, err = json.Marshal(dropNulls(fields))
+	if err != nil {
+		return err
+	}
+
+	merged, err := strategicpatch.StrategicMergePatch(original, patch, v13.PodTemplateSpec{})
+	if err != nil {
+		return fmt.Errorf("failed to merge pod template. %w", err)
+	}
+
+	result := v13.PodTemplateSpec{}
+	if err = json.Unmarshal(merged, &result); err != nil {
+		return err
+	}
+
+	*template = result
+	return nil
+}
+
+func dropNulls(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if field == nil {
+				delete(v, key)
+			} else {
+				v[key] = dropNulls(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = dropNulls(item)
+		}
+	}
+
+	return value
+}
+
 // EscapeString escapes a value using json.Marshal to ensure it's properly escaped.
 // For PEM and key formats, it preserves newlines and special characters.
 func EscapeString(value string) (string, error) {
@@ -2,7 +2,12 @@ package resources
 
 import (
 	"bytes"
+	"reflect"
 	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 func TestEscapeString(t *testing.T) {
@@ -86,3 +91,199 @@ word`,
 		})
 	}
 }
+
+func TestMergeResourceRequirements(t *testing.T) {
+	base := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("1"),
+			corev1.ResourceMemory: resource.MustParse("1250Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2500m"),
+			corev1.ResourceMemory: resource.MustParse("1360Mi"),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		override *corev1.ResourceRequirements
+		want     corev1.ResourceRequirements
+	}{
+		{
+			name:     "no override",
+			override: nil,
+			want:     base,
+		},
+		{
+			name: "override single resource",
+			override: &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("4"),
+				},
+			},
+			want: corev1.ResourceRequirements{
+				Requests: base.Requests,
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("1360Mi"),
+				},
+			},
+		},
+		{
+			name: "add ephemeral storage",
+			override: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+				},
+			},
+			want: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:              resource.MustParse("1"),
+					corev1.ResourceMemory:           resource.MustParse("1250Mi"),
+					corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:              resource.MustParse("2500m"),
+					corev1.ResourceMemory:           resource.MustParse("1360Mi"),
+					corev1.ResourceEphemeralStorage: resource.MustParse("2Gi"),
+				},
+			},
+		},
+		{
+			name: "raise limit below request",
+			override: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("2Gi"),
+				},
+			},
+			want: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("2Gi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2500m"),
+					corev1.ResourceMemory: resource.MustParse("2Gi"),
+				},
+			},
+		},
+		{
+			name: "keep explicit limit",
+			override: &corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("2Gi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+			},
+			want: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("2Gi"),
+				},
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2500m"),
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MergeResourceRequirements(base, tt.override)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MergeResourceRequirements() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePodTemplate(t *testing.T) {
+	newTemplate := func() corev1.PodTemplateSpec {
+		return corev1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: map[string]string{"app": "rhbk"},
+			},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						Name:  "keycloak",
+						Image: "rhbk",
+						Env:   []corev1.EnvVar{{Name: "KC_CACHE", Value: "ispn"}},
+					},
+				},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		overlay *corev1.PodTemplateSpec
+		want    func(*corev1.PodTemplateSpec)
+	}{
+		{
+			name:    "no overlay",
+			overlay: nil,
+			want:    func(*corev1.PodTemplateSpec) {},
+		},
+		{
+			name: "metadata and pod fields are added",
+			overlay: &corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Annotations: map[string]string{"sidecar.istio.io/inject": "false"},
+				},
+				Spec: corev1.PodSpec{
+					HostAliases: []corev1.HostAlias{{IP: "10.0.0.1", Hostnames: []string{"ldap"}}},
+				},
+			},
+			want: func(t *corev1.PodTemplateSpec) {
+				t.Annotations = map[string]string{"sidecar.istio.io/inject": "false"}
+				t.Spec.HostAliases = []corev1.HostAlias{{IP: "10.0.0.1", Hostnames: []string{"ldap"}}}
+			},
+		},
+		{
+			name: "containers are merged by name",
+			overlay: &corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{
+						{
+							Name: "keycloak",
+							Env:  []corev1.EnvVar{{Name: "KC_LOG_LEVEL", Value: "debug"}},
+						},
+						{
+							Name:  "proxy",
+							Image: "proxy",
+						},
+					},
+				},
+			},
+			want: func(t *corev1.PodTemplateSpec) {
+				t.Spec.Containers[0].Env = []corev1.EnvVar{
+					{Name: "KC_LOG_LEVEL", Value: "debug"},
+					{Name: "KC_CACHE", Value: "ispn"},
+				}
+				t.Spec.Containers = append(t.Spec.Containers, corev1.Container{Name: "proxy", Image: "proxy"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newTemplate()
+			if err := MergePodTemplate(&got, tt.overlay); err != nil {
+				t.Fatalf("MergePodTemplate() error = %v", err)
+			}
+
+			want := newTemplate()
+			tt.want(&want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("MergePodTemplate() = %v, want %v", FormatResource(got), FormatResource(want))
+			}
+		})
+	}
+}
@@ -30,6 +30,7 @@ var excludeFields = []string{
 	"$.status.lastSuccessfulTime",
 	"$.status.conditions[*].lastUpdateTime",
 	"$.status.ingress",
+	"$.status.checks",
 }
 
 var excludeJobFields = append(excludeFields, []string{